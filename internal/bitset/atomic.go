@@ -0,0 +1,90 @@
+package bitset
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+)
+
+// Atomic is a fixed-size bitset packed in 64-bit words that is safe for
+// concurrent use: Set updates a word with an atomic OR, so concurrent Sets
+// never lose each other's bits, and readers load whole words atomically.
+// Bit i lives in the same place as in Bitset once the words are written out
+// little-endian, so the two share a serialized form.
+type Atomic struct {
+	words   []atomic.Uint64
+	bitsize uint64
+}
+
+// NewAtomic returns a zeroed atomic bitset of bitsize bits.
+func NewAtomic(bitsize uint64) *Atomic {
+	return &Atomic{
+		words:   make([]atomic.Uint64, (bitsize+63)/64),
+		bitsize: bitsize,
+	}
+}
+
+func (a *Atomic) Size() uint64 {
+	return a.bitsize
+}
+
+// Set sets the bit at index and reports whether it was previously unset.
+// A bit that is already set is only read, so setting hot bits does not
+// contend for the cache line.
+func (a *Atomic) Set(index uint64) (bool, error) {
+	if index >= a.bitsize {
+		return false, fmt.Errorf("index out of range: %d", index)
+	}
+
+	word, mask := &a.words[index/64], uint64(1)<<(index%64)
+	if word.Load()&mask != 0 {
+		return false, nil
+	}
+	return word.Or(mask)&mask == 0, nil
+}
+
+func (a *Atomic) IsSet(index uint64) (bool, error) {
+	if index >= a.bitsize {
+		return false, fmt.Errorf("index out of range: %d", index)
+	}
+
+	return a.words[index/64].Load()&(1<<(index%64)) != 0, nil
+}
+
+// Count returns the number of set bits. Bits set while it runs may or may
+// not be counted.
+func (a *Atomic) Count() uint64 {
+	var count int
+	for i := range a.words {
+		count += bits.OnesCount64(a.words[i].Load())
+	}
+	return uint64(count)
+}
+
+// Bytes returns a copy of the bits in the byte layout of Bitset.List. Bits
+// set while it runs may or may not be included.
+func (a *Atomic) Bytes() []byte {
+	b := make([]byte, 0, len(a.words)*8)
+	for i := range a.words {
+		b = binary.LittleEndian.AppendUint64(b, a.words[i].Load())
+	}
+	return b[:(a.bitsize+7)/8]
+}
+
+// AtomicFromBytes returns an atomic bitset of bitsize bits holding a copy of
+// bits, as returned by Bitset.List. It fails like FromBytes.
+func AtomicFromBytes(bits []byte, bitsize uint64) (*Atomic, error) {
+	if _, err := FromBytes(bits, bitsize); err != nil {
+		return nil, err
+	}
+
+	a := NewAtomic(bitsize)
+	var word [8]byte
+	for i := range a.words {
+		clear(word[:])
+		copy(word[:], bits[min(8*i, len(bits)):])
+		a.words[i].Store(binary.LittleEndian.Uint64(word[:]))
+	}
+	return a, nil
+}
@@ -0,0 +1,86 @@
+package bitset
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestAtomicMatchesBitset(t *testing.T) {
+	for _, size := range []uint64{1, 63, 64, 65, 100, 1000} {
+		a := NewAtomic(size)
+		bs := NewBitset(size)
+		for i := uint64(0); i < size; i += 3 {
+			set, err := a.Set(i)
+			if err != nil || !set {
+				t.Fatalf("size %d: Set(%d) = %v, %v, want true, nil", size, i, set, err)
+			}
+			if set, _ := a.Set(i); set {
+				t.Fatalf("size %d: second Set(%d) = true, want false", size, i)
+			}
+			bs.Set(i)
+		}
+
+		if !bytes.Equal(a.Bytes(), bs.List()) {
+			t.Errorf("size %d: Bytes() = %x, want %x", size, a.Bytes(), bs.List())
+		}
+		if a.Count() != bs.Count() {
+			t.Errorf("size %d: Count() = %d, want %d", size, a.Count(), bs.Count())
+		}
+		for i := uint64(0); i < size; i++ {
+			if got, _ := a.IsSet(i); got != (i%3 == 0) {
+				t.Fatalf("size %d: IsSet(%d) = %v, want %v", size, i, got, i%3 == 0)
+			}
+		}
+
+		b, err := AtomicFromBytes(bs.List(), size)
+		if err != nil {
+			t.Fatalf("size %d: AtomicFromBytes() error = %v", size, err)
+		}
+		if !bytes.Equal(b.Bytes(), bs.List()) {
+			t.Errorf("size %d: AtomicFromBytes() round trip = %x, want %x", size, b.Bytes(), bs.List())
+		}
+	}
+}
+
+func TestAtomicOutOfRange(t *testing.T) {
+	a := NewAtomic(10)
+	if _, err := a.Set(10); err == nil {
+		t.Error("Set(10) error = nil, want error")
+	}
+	if _, err := a.IsSet(10); err == nil {
+		t.Error("IsSet(10) error = nil, want error")
+	}
+	if _, err := AtomicFromBytes([]byte{0, 0xff}, 10); err == nil {
+		t.Error("AtomicFromBytes() with padding set error = nil, want error")
+	}
+}
+
+func TestAtomicConcurrentSet(t *testing.T) {
+	const goroutines, size = 8, 4096
+	a := NewAtomic(size)
+	newlySet := make([]int, goroutines)
+
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Every goroutine sets every bit, interleaved within words.
+			for i := uint64(0); i < size; i++ {
+				if set, _ := a.Set((i*7 + uint64(g)) % size); set {
+					newlySet[g]++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total int
+	for _, n := range newlySet {
+		total += n
+	}
+	if total != size || a.Count() != size {
+		t.Errorf("bits reported newly set = %d, Count() = %d, want %d each", total, a.Count(), size)
+	}
+}
@@ -1,7 +1,9 @@
 package bitset
 
 import (
+	"encoding/binary"
 	"fmt"
+	"math/bits"
 )
 
 // Bitset implementation, bit sequence packed in byte slice.
@@ -9,17 +11,17 @@ import (
 // for each chunk, just to keep simpler formula.
 type Bitset struct {
 	bits    []byte
-	bitsize uint32
+	bitsize uint64
 }
 
-func NewBitset(bitsize uint32) *Bitset {
+func NewBitset(bitsize uint64) *Bitset {
 	return &Bitset{
 		bits:    make([]byte, (bitsize+7)/8),
 		bitsize: bitsize,
 	}
 }
 
-func (bs *Bitset) Size() uint32 {
+func (bs *Bitset) Size() uint64 {
 	return bs.bitsize
 }
 
@@ -27,7 +29,7 @@ func (bs *Bitset) List() []byte {
 	return bs.bits
 }
 
-func (bs *Bitset) Set(index uint32) error {
+func (bs *Bitset) Set(index uint64) error {
 	if index >= bs.Size() {
 		return fmt.Errorf("index out of range: %d", index)
 	}
@@ -36,7 +38,7 @@ func (bs *Bitset) Set(index uint32) error {
 	return nil
 }
 
-func (bs *Bitset) Unset(index uint32) error {
+func (bs *Bitset) Unset(index uint64) error {
 	if index >= bs.Size() {
 		return fmt.Errorf("index out of range: %d", index)
 	}
@@ -45,7 +47,7 @@ func (bs *Bitset) Unset(index uint32) error {
 	return nil
 }
 
-func (bs *Bitset) Toggle(index uint32) error {
+func (bs *Bitset) Toggle(index uint64) error {
 	if index >= bs.Size() {
 		return fmt.Errorf("index out of range: %d", index)
 	}
@@ -54,10 +56,115 @@ func (bs *Bitset) Toggle(index uint32) error {
 	return nil
 }
 
-func (bs *Bitset) IsSet(index uint32) (bool, error) {
+func (bs *Bitset) IsSet(index uint64) (bool, error) {
 	if index >= bs.Size() {
 		return false, fmt.Errorf("index out of range: %d", index)
 	}
 
 	return (bs.bits[index/8] & (1 << (index % 8))) != 0, nil
 }
+
+// Count returns the number of set bits.
+func (bs *Bitset) Count() uint64 {
+	return popcount(bs.bits)
+}
+
+// CountRange returns the number of set bits in [from, to).
+func (bs *Bitset) CountRange(from, to uint64) (uint64, error) {
+	if from > to || to > bs.Size() {
+		return 0, fmt.Errorf("range out of bounds: [%d, %d)", from, to)
+	}
+	if from == to {
+		return 0, nil
+	}
+
+	first, last := from/8, (to-1)/8
+	head := bs.bits[first] &^ (1<<(from%8) - 1)
+	if first == last {
+		head &= 0xff >> (7 - (to-1)%8)
+		return uint64(bits.OnesCount8(head)), nil
+	}
+	tail := bs.bits[last] & (0xff >> (7 - (to-1)%8))
+	count := uint64(bits.OnesCount8(head)+bits.OnesCount8(tail)) + popcount(bs.bits[first+1:last])
+	return count, nil
+}
+
+func popcount(b []byte) uint64 {
+	var count int
+	for len(b) >= 8 {
+		count += bits.OnesCount64(binary.LittleEndian.Uint64(b))
+		b = b[8:]
+	}
+	for _, c := range b {
+		count += bits.OnesCount8(c)
+	}
+	return uint64(count)
+}
+
+// FromBytes returns a bitset of bitsize bits backed by bits, as returned by
+// List. It fails if bits is empty, the length does not match or padding
+// bits are set.
+func FromBytes(bits []byte, bitsize uint64) (*Bitset, error) {
+	if len(bits) == 0 {
+		return nil, fmt.Errorf("bitset of %d bits has no bytes", bitsize)
+	}
+	// Rounded up without adding to bitsize, which may be near 2^64.
+	need := bitsize/8 + min(bitsize%8, 1)
+	if uint64(len(bits)) != need {
+		return nil, fmt.Errorf("bitset of %d bits needs %d bytes, got %d", bitsize, need, len(bits))
+	}
+	if pad := bitsize % 8; pad != 0 && bits[len(bits)-1]>>pad != 0 {
+		return nil, fmt.Errorf("padding bits set beyond bit %d", bitsize)
+	}
+
+	return &Bitset{
+		bits:    bits,
+		bitsize: bitsize,
+	}, nil
+}
+
+// Clone returns a copy of the bitset.
+func (bs *Bitset) Clone() *Bitset {
+	return &Bitset{
+		bits:    append([]byte(nil), bs.bits...),
+		bitsize: bs.bitsize,
+	}
+}
+
+// Or sets every bit that is set in other.
+func (bs *Bitset) Or(other *Bitset) error {
+	if other.Size() != bs.Size() {
+		return fmt.Errorf("size mismatch: %d and %d", bs.Size(), other.Size())
+	}
+
+	for i, b := range other.bits {
+		bs.bits[i] |= b
+	}
+	return nil
+}
+
+// And clears every bit that is not set in other.
+func (bs *Bitset) And(other *Bitset) error {
+	if other.Size() != bs.Size() {
+		return fmt.Errorf("size mismatch: %d and %d", bs.Size(), other.Size())
+	}
+
+	for i, b := range other.bits {
+		bs.bits[i] &= b
+	}
+	return nil
+}
+
+// OrCount returns the number of bits set in either bs or other,
+// without modifying either.
+func (bs *Bitset) OrCount(other *Bitset) (uint64, error) {
+	if other.Size() != bs.Size() {
+		return 0, fmt.Errorf("size mismatch: %d and %d", bs.Size(), other.Size())
+	}
+
+	var count int
+	for i, b := range other.bits {
+		count += bits.OnesCount8(bs.bits[i] | b)
+	}
+	return uint64(count), nil
+}
@@ -1,14 +1,15 @@
 package bitset
 
 import (
+	"math"
 	"testing"
 )
 
 func TestBitsetNew(t *testing.T) {
 	tests := []struct {
 		name     string
-		size     uint32
-		wantSize uint32
+		size     uint64
+		wantSize uint64
 		wantLen  int
 	}{
 		{
@@ -68,8 +69,8 @@ func TestBitsetNew(t *testing.T) {
 func TestBitsetSet(t *testing.T) {
 	tests := []struct {
 		name      string
-		size      uint32
-		setIndex  uint32
+		size      uint64
+		setIndex  uint64
 		wantError bool
 	}{
 		{
@@ -128,16 +129,16 @@ func TestBitsetSet(t *testing.T) {
 func TestBitsetIsSet(t *testing.T) {
 	tests := []struct {
 		name      string
-		size      uint32
-		setBits   []uint32
-		checkBit  uint32
+		size      uint64
+		setBits   []uint64
+		checkBit  uint64
 		wantSet   bool
 		wantError bool
 	}{
 		{
 			name:      "check set bit",
 			size:      16,
-			setBits:   []uint32{5},
+			setBits:   []uint64{5},
 			checkBit:  5,
 			wantSet:   true,
 			wantError: false,
@@ -145,7 +146,7 @@ func TestBitsetIsSet(t *testing.T) {
 		{
 			name:      "check unset bit",
 			size:      16,
-			setBits:   []uint32{5},
+			setBits:   []uint64{5},
 			checkBit:  6,
 			wantSet:   false,
 			wantError: false,
@@ -153,7 +154,7 @@ func TestBitsetIsSet(t *testing.T) {
 		{
 			name:      "check multiple set bits",
 			size:      16,
-			setBits:   []uint32{0, 5, 10, 15},
+			setBits:   []uint64{0, 5, 10, 15},
 			checkBit:  10,
 			wantSet:   true,
 			wantError: false,
@@ -161,7 +162,7 @@ func TestBitsetIsSet(t *testing.T) {
 		{
 			name:      "check out of range",
 			size:      16,
-			setBits:   []uint32{},
+			setBits:   []uint64{},
 			checkBit:  16,
 			wantSet:   false,
 			wantError: true,
@@ -169,7 +170,7 @@ func TestBitsetIsSet(t *testing.T) {
 		{
 			name:      "check first bit",
 			size:      100,
-			setBits:   []uint32{0, 50, 99},
+			setBits:   []uint64{0, 50, 99},
 			checkBit:  0,
 			wantSet:   true,
 			wantError: false,
@@ -177,7 +178,7 @@ func TestBitsetIsSet(t *testing.T) {
 		{
 			name:      "check last bit",
 			size:      100,
-			setBits:   []uint32{0, 50, 99},
+			setBits:   []uint64{0, 50, 99},
 			checkBit:  99,
 			wantSet:   true,
 			wantError: false,
@@ -207,16 +208,16 @@ func TestBitsetIsSet(t *testing.T) {
 func TestBitsetUnset(t *testing.T) {
 	tests := []struct {
 		name         string
-		size         uint32
-		setBits      []uint32
-		unsetBit     uint32
+		size         uint64
+		setBits      []uint64
+		unsetBit     uint64
 		wantError    bool
 		wantSetAfter bool
 	}{
 		{
 			name:         "unset set bit",
 			size:         16,
-			setBits:      []uint32{5},
+			setBits:      []uint64{5},
 			unsetBit:     5,
 			wantError:    false,
 			wantSetAfter: false,
@@ -224,7 +225,7 @@ func TestBitsetUnset(t *testing.T) {
 		{
 			name:         "unset already unset bit",
 			size:         16,
-			setBits:      []uint32{5},
+			setBits:      []uint64{5},
 			unsetBit:     6,
 			wantError:    false,
 			wantSetAfter: false,
@@ -232,7 +233,7 @@ func TestBitsetUnset(t *testing.T) {
 		{
 			name:         "unset out of range",
 			size:         16,
-			setBits:      []uint32{},
+			setBits:      []uint64{},
 			unsetBit:     16,
 			wantError:    true,
 			wantSetAfter: false,
@@ -240,7 +241,7 @@ func TestBitsetUnset(t *testing.T) {
 		{
 			name:         "unset one of many",
 			size:         32,
-			setBits:      []uint32{0, 5, 10, 15, 20},
+			setBits:      []uint64{0, 5, 10, 15, 20},
 			unsetBit:     10,
 			wantError:    false,
 			wantSetAfter: false,
@@ -276,16 +277,16 @@ func TestBitsetUnset(t *testing.T) {
 func TestBitsetToggle(t *testing.T) {
 	tests := []struct {
 		name         string
-		size         uint32
-		initialSet   []uint32
-		toggleBit    uint32
+		size         uint64
+		initialSet   []uint64
+		toggleBit    uint64
 		wantError    bool
 		wantSetAfter bool
 	}{
 		{
 			name:         "toggle unset to set",
 			size:         16,
-			initialSet:   []uint32{},
+			initialSet:   []uint64{},
 			toggleBit:    5,
 			wantError:    false,
 			wantSetAfter: true,
@@ -293,7 +294,7 @@ func TestBitsetToggle(t *testing.T) {
 		{
 			name:         "toggle set to unset",
 			size:         16,
-			initialSet:   []uint32{5},
+			initialSet:   []uint64{5},
 			toggleBit:    5,
 			wantError:    false,
 			wantSetAfter: false,
@@ -301,7 +302,7 @@ func TestBitsetToggle(t *testing.T) {
 		{
 			name:         "toggle out of range",
 			size:         16,
-			initialSet:   []uint32{},
+			initialSet:   []uint64{},
 			toggleBit:    16,
 			wantError:    true,
 			wantSetAfter: false,
@@ -336,7 +337,7 @@ func TestBitsetToggle(t *testing.T) {
 
 func TestBitsetToggleTwice(t *testing.T) {
 	bs := NewBitset(16)
-	idx := uint32(5)
+	idx := uint64(5)
 
 	// Initially unset
 	isSet, _ := bs.IsSet(idx)
@@ -366,10 +367,10 @@ func TestBitsetToggleTwice(t *testing.T) {
 func TestBitsetOperationsSequence(t *testing.T) {
 	tests := []struct {
 		name       string
-		size       uint32
+		size       uint64
 		operations []struct {
 			op      string // "set", "unset", "toggle"
-			index   uint32
+			index   uint64
 			wantSet bool
 		}
 	}{
@@ -378,7 +379,7 @@ func TestBitsetOperationsSequence(t *testing.T) {
 			size: 16,
 			operations: []struct {
 				op      string
-				index   uint32
+				index   uint64
 				wantSet bool
 			}{
 				{op: "set", index: 5, wantSet: true},
@@ -390,7 +391,7 @@ func TestBitsetOperationsSequence(t *testing.T) {
 			size: 16,
 			operations: []struct {
 				op      string
-				index   uint32
+				index   uint64
 				wantSet bool
 			}{
 				{op: "toggle", index: 5, wantSet: true},
@@ -403,7 +404,7 @@ func TestBitsetOperationsSequence(t *testing.T) {
 			size: 32,
 			operations: []struct {
 				op      string
-				index   uint32
+				index   uint64
 				wantSet bool
 			}{
 				{op: "set", index: 10, wantSet: true},
@@ -447,18 +448,18 @@ func BenchmarkBitsetSet(b *testing.B) {
 	bs := NewBitset(1024)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		bs.Set(uint32(i % 1024))
+		bs.Set(uint64(i % 1024))
 	}
 }
 
 func BenchmarkBitsetIsSet(b *testing.B) {
 	bs := NewBitset(1024)
-	for i := uint32(0); i < 1024; i++ {
+	for i := uint64(0); i < 1024; i++ {
 		bs.Set(i)
 	}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		bs.IsSet(uint32(i % 1024))
+		bs.IsSet(uint64(i % 1024))
 	}
 }
 
@@ -466,6 +467,178 @@ func BenchmarkBitsetToggle(b *testing.B) {
 	bs := NewBitset(1024)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		bs.Toggle(uint32(i % 1024))
+		bs.Toggle(uint64(i % 1024))
+	}
+}
+
+func TestBitsetCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      uint64
+		setBits   []uint64
+		wantCount uint64
+	}{
+		{"empty", 64, nil, 0},
+		{"single bit", 64, []uint64{5}, 1},
+		{"same bit twice", 64, []uint64{5, 5}, 1},
+		{"across words", 200, []uint64{0, 63, 64, 130, 199}, 5},
+		{"tail bytes", 20, []uint64{0, 8, 16, 19}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs := NewBitset(tt.size)
+			for _, i := range tt.setBits {
+				if err := bs.Set(i); err != nil {
+					t.Fatalf("Set(%d) error = %v", i, err)
+				}
+			}
+			if got := bs.Count(); got != tt.wantCount {
+				t.Errorf("Count() = %d, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestBitsetCountRange(t *testing.T) {
+	bs := NewBitset(200)
+	for _, i := range []uint64{0, 3, 7, 8, 15, 16, 63, 64, 100, 130, 199} {
+		bs.Set(i)
+	}
+
+	for from := uint64(0); from <= 200; from += 7 {
+		for to := from; to <= 200; to += 5 {
+			var want uint64
+			for i := from; i < to; i++ {
+				if set, _ := bs.IsSet(i); set {
+					want++
+				}
+			}
+			got, err := bs.CountRange(from, to)
+			if err != nil {
+				t.Fatalf("CountRange(%d, %d) error = %v", from, to, err)
+			}
+			if got != want {
+				t.Errorf("CountRange(%d, %d) = %d, want %d", from, to, got, want)
+			}
+		}
+	}
+
+	for _, r := range [][2]uint64{{5, 4}, {0, 201}} {
+		if _, err := bs.CountRange(r[0], r[1]); err == nil {
+			t.Errorf("CountRange(%d, %d) error = nil, want out of bounds", r[0], r[1])
+		}
+	}
+}
+
+func BenchmarkBitsetCount(b *testing.B) {
+	bs := NewBitset(1 << 16)
+	for i := uint64(0); i < 1<<16; i += 3 {
+		bs.Set(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bs.Count()
+	}
+}
+
+func TestBitsetFromBytes(t *testing.T) {
+	tests := []struct {
+		name      string
+		bits      []byte
+		size      uint64
+		wantError bool
+	}{
+		{"exact bytes", []byte{0xff, 0x01}, 16, false},
+		{"partial byte", []byte{0xff, 0x0f}, 12, false},
+		{"too short", []byte{0xff}, 16, true},
+		{"too long", []byte{0xff, 0x00, 0x00}, 16, true},
+		{"padding set", []byte{0xff, 0x10}, 12, true},
+		{"empty", nil, 0, true},
+		{"size wraps", nil, math.MaxUint64, true},
+		{"size near 2^64", []byte{0xff}, math.MaxUint64, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs, err := FromBytes(tt.bits, tt.size)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("FromBytes() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !tt.wantError && bs.Size() != tt.size {
+				t.Errorf("Size() = %d, want %d", bs.Size(), tt.size)
+			}
+		})
+	}
+}
+
+func TestBitsetOrAnd(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    []uint64
+		wantOr  []uint64
+		wantAnd []uint64
+	}{
+		{"disjoint", []uint64{1, 3}, []uint64{2, 4}, []uint64{1, 2, 3, 4}, nil},
+		{"overlapping", []uint64{1, 3, 9}, []uint64{3, 9, 15}, []uint64{1, 3, 9, 15}, []uint64{3, 9}},
+		{"empty other", []uint64{5}, nil, []uint64{5}, nil},
+	}
+
+	build := func(set []uint64) *Bitset {
+		bs := NewBitset(16)
+		for _, i := range set {
+			bs.Set(i)
+		}
+		return bs
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			or := build(tt.a)
+			if err := or.Or(build(tt.b)); err != nil {
+				t.Fatalf("Or() error = %v", err)
+			}
+			if got, want := or.List(), build(tt.wantOr).List(); string(got) != string(want) {
+				t.Errorf("Or() = %08b, want %08b", got, want)
+			}
+
+			if got, err := build(tt.a).OrCount(build(tt.b)); err != nil || got != uint64(len(tt.wantOr)) {
+				t.Errorf("OrCount() = %d, %v, want %d", got, err, len(tt.wantOr))
+			}
+
+			and := build(tt.a)
+			if err := and.And(build(tt.b)); err != nil {
+				t.Fatalf("And() error = %v", err)
+			}
+			if got, want := and.List(), build(tt.wantAnd).List(); string(got) != string(want) {
+				t.Errorf("And() = %08b, want %08b", got, want)
+			}
+		})
+	}
+
+	t.Run("size mismatch", func(t *testing.T) {
+		if err := NewBitset(16).Or(NewBitset(8)); err == nil {
+			t.Error("Or() error = nil, want error")
+		}
+		if err := NewBitset(16).And(NewBitset(8)); err == nil {
+			t.Error("And() error = nil, want error")
+		}
+		if _, err := NewBitset(16).OrCount(NewBitset(8)); err == nil {
+			t.Error("OrCount() error = nil, want error")
+		}
+	})
+}
+
+func TestBitsetClone(t *testing.T) {
+	bs := NewBitset(16)
+	bs.Set(3)
+	clone := bs.Clone()
+	clone.Set(4)
+
+	if set, _ := bs.IsSet(4); set {
+		t.Error("Set() on clone changed the original")
+	}
+	if set, _ := clone.IsSet(3); !set {
+		t.Error("clone lost bit 3")
 	}
 }
@@ -0,0 +1,189 @@
+// Package fuse builds the 3-wise binary fuse graphs behind static filters
+// and maps: each key hashes to three slots in consecutive segments of an
+// array, and peeling the resulting hypergraph yields an order in which every
+// key can be given a slot of its own so that the XOR of its three slots
+// equals any value chosen for it.
+// see Graf & Lemire, "Binary Fuse Filters: Fast and Smaller Than Xor Filters"
+package fuse
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// maxSegmentLength caps the segment length for very large key sets.
+const maxSegmentLength = 1 << 18
+
+// maxAttempts bounds the seeds tried before construction gives up. Each
+// attempt fails with small probability once duplicates are ruled out.
+const maxAttempts = 100
+
+var (
+	// ErrDuplicate is returned when two keys have the same hash.
+	ErrDuplicate = errors.New("duplicate hash")
+	// ErrPeel is returned when no seed yields a peelable graph.
+	ErrPeel = errors.New("graph could not be peeled")
+	// ErrTooLarge is returned when there are too many keys for 32-bit slots.
+	ErrTooLarge = errors.New("too many keys")
+)
+
+// Layout describes the slot array for a number of keys: SegmentCount+2
+// segments of SegmentLength slots, where a key's slots lie in three
+// consecutive segments.
+type Layout struct {
+	SegmentLength      uint32
+	SegmentCount       uint32
+	SegmentCountLength uint32
+	ArrayLength        uint32
+}
+
+// NewLayout returns the layout for n keys.
+func NewLayout(n uint32) Layout {
+	var l Layout
+	if n == 0 {
+		l.SegmentLength = 4
+	} else {
+		l.SegmentLength = 1 << int(math.Floor(math.Log(float64(n))/math.Log(3.33)+2.25))
+	}
+	l.SegmentLength = min(l.SegmentLength, maxSegmentLength)
+
+	var capacity uint64
+	if n > 1 {
+		factor := max(1.125, 0.875+0.25*math.Log(1e6)/math.Log(float64(n)))
+		capacity = uint64(math.Round(float64(n) * factor))
+	}
+	segments := (capacity + uint64(l.SegmentLength) - 1) / uint64(l.SegmentLength)
+	l.SegmentCount = uint32(max(segments, 3) - 2)
+	l.SegmentCountLength = l.SegmentCount * l.SegmentLength
+	l.ArrayLength = (l.SegmentCount + 2) * l.SegmentLength
+	return l
+}
+
+// Positions returns the three slots of a mixed hash, one in each of three
+// consecutive segments.
+func (l Layout) Positions(h uint64) [3]uint32 {
+	hi, _ := bits.Mul64(h, uint64(l.SegmentCountLength))
+	mask := l.SegmentLength - 1
+	h0 := uint32(hi)
+	h1 := h0 + l.SegmentLength
+	h2 := h1 + l.SegmentLength
+	h1 ^= uint32(h>>18) & mask
+	h2 ^= uint32(h) & mask
+	return [3]uint32{h0, h1, h2}
+}
+
+// Mix derives the hash of a key for one construction attempt from its base
+// hash and the attempt's seed.
+func Mix(h, seed uint64) uint64 {
+	h += seed
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// splitmix64 advances state and returns the next seed.
+func splitmix64(state *uint64) uint64 {
+	*state += 0x9e3779b97f4a7c15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Step assigns one key its own slot. Steps must be applied in order: when a
+// step is reached, the key's other two slots already hold their final
+// values, so setting
+//
+//	array[Slot] = value ^ array[p0] ^ array[p1] ^ array[p2]
+//
+// over the key's Positions(Hash), with array[Slot] still zero, makes their
+// XOR equal value.
+type Step struct {
+	// Key is the index of the key in the slice passed to Build.
+	Key int
+	// Hash is the key's mixed hash.
+	Hash uint64
+	// Slot is the slot assigned to the key.
+	Slot uint32
+}
+
+// Build peels the graph of the keys with the given base hashes. It returns
+// the layout, the seed to mix hashes with and the assignment steps.
+// Duplicate hashes make the graph unpeelable and are reported as ErrDuplicate.
+func Build(hashes []uint64) (Layout, uint64, []Step, error) {
+	if uint64(len(hashes)) > math.MaxUint32/2 {
+		return Layout{}, 0, nil, ErrTooLarge
+	}
+	sorted := slices.Clone(hashes)
+	slices.Sort(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return Layout{}, 0, nil, ErrDuplicate
+		}
+	}
+
+	l := NewLayout(uint32(len(hashes)))
+	var state uint64
+	for range maxAttempts {
+		seed := splitmix64(&state)
+		if steps, ok := peel(l, hashes, seed); ok {
+			return l, seed, steps, nil
+		}
+	}
+	return Layout{}, 0, nil, ErrPeel
+}
+
+// peel repeatedly removes keys that are alone in one of their slots. It
+// succeeds if every key is removed, returning the steps in assignment order,
+// the reverse of removal.
+func peel(l Layout, hashes []uint64, seed uint64) ([]Step, bool) {
+	count := make([]uint32, l.ArrayLength)
+	xorHash := make([]uint64, l.ArrayLength)
+	xorKey := make([]int, l.ArrayLength)
+	for i, base := range hashes {
+		h := Mix(base, seed)
+		for _, p := range l.Positions(h) {
+			count[p]++
+			xorHash[p] ^= h
+			xorKey[p] ^= i
+		}
+	}
+
+	queue := make([]uint32, 0, len(hashes))
+	for p, c := range count {
+		if c == 1 {
+			queue = append(queue, uint32(p))
+		}
+	}
+
+	steps := make([]Step, 0, len(hashes))
+	for len(queue) > 0 {
+		p := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if count[p] != 1 {
+			continue
+		}
+
+		h, key := xorHash[p], xorKey[p]
+		steps = append(steps, Step{Key: key, Hash: h, Slot: p})
+		for _, q := range l.Positions(h) {
+			count[q]--
+			xorHash[q] ^= h
+			xorKey[q] ^= key
+			if count[q] == 1 {
+				queue = append(queue, q)
+			}
+		}
+	}
+	if len(steps) != len(hashes) {
+		return nil, false
+	}
+
+	slices.Reverse(steps)
+	return steps, true
+}
@@ -0,0 +1,74 @@
+package fuse
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	for _, n := range []uint32{0, 1, 2, 10, 1000, 100000, 3000000} {
+		l := NewLayout(n)
+		if l.SegmentLength&(l.SegmentLength-1) != 0 || l.SegmentLength > maxSegmentLength {
+			t.Errorf("n=%d: SegmentLength = %d, want a power of two up to %d", n, l.SegmentLength, maxSegmentLength)
+		}
+		if l.ArrayLength != (l.SegmentCount+2)*l.SegmentLength {
+			t.Errorf("n=%d: ArrayLength = %d, want %d", n, l.ArrayLength, (l.SegmentCount+2)*l.SegmentLength)
+		}
+		if n > 1000 && float64(l.ArrayLength) > 1.25*float64(n) {
+			t.Errorf("n=%d: ArrayLength = %d, want at most 1.25n", n, l.ArrayLength)
+		}
+
+		r := rand.New(rand.NewPCG(uint64(n), 0))
+		for i := 0; i < 1000; i++ {
+			ps := l.Positions(r.Uint64())
+			for j, p := range ps {
+				if p >= l.ArrayLength {
+					t.Fatalf("n=%d: position %d out of range %d", n, p, l.ArrayLength)
+				}
+				if j > 0 && p/l.SegmentLength != ps[0]/l.SegmentLength+uint32(j) {
+					t.Fatalf("n=%d: positions %v not in consecutive segments", n, ps)
+				}
+			}
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 100, 10000, 200000} {
+		r := rand.New(rand.NewPCG(uint64(n), 1))
+		hashes := make([]uint64, n)
+		values := make([]uint16, n)
+		for i := range hashes {
+			hashes[i] = r.Uint64()
+			values[i] = uint16(r.Uint32())
+		}
+
+		l, seed, steps, err := Build(hashes)
+		if err != nil {
+			t.Fatalf("n=%d: Build() error = %v", n, err)
+		}
+		if len(steps) != n {
+			t.Fatalf("n=%d: len(steps) = %d, want %d", n, len(steps), n)
+		}
+
+		array := make([]uint16, l.ArrayLength)
+		for _, s := range steps {
+			ps := l.Positions(s.Hash)
+			array[s.Slot] = values[s.Key] ^ array[ps[0]] ^ array[ps[1]] ^ array[ps[2]]
+		}
+		for i, h := range hashes {
+			ps := l.Positions(Mix(h, seed))
+			if got := array[ps[0]] ^ array[ps[1]] ^ array[ps[2]]; got != values[i] {
+				t.Fatalf("n=%d: key %d decodes to %d, want %d", n, i, got, values[i])
+			}
+		}
+	}
+}
+
+func TestBuild_Duplicate(t *testing.T) {
+	hashes := []uint64{1, 2, 3, 2}
+	if _, _, _, err := Build(hashes); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Build() error = %v, want %v", err, ErrDuplicate)
+	}
+}
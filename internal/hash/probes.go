@@ -0,0 +1,35 @@
+package hash
+
+import "math/bits"
+
+// Probes derives a sequence of bit positions in [0, m) from a single 128-bit
+// digest using enhanced double hashing:
+//
+//	g_i = h1 + i*h2 + (i^3 - i)/6 (mod 2^64)
+//
+// so a key is hashed once no matter how many positions the filter needs.
+// Each g_i is mapped onto [0, m) by multiply-shift rather than modulo, which
+// keeps the full 64 bits of state even for small filters.
+// see Kirsch & Mitzenmacher, "Less Hashing, Same Performance: Building a Better Bloom Filter"
+// and Dillinger & Manolios, "Bloom Filters in Probabilistic Verification".
+type Probes struct {
+	x, y, m, i uint64
+}
+
+// NewProbes starts a probe sequence over m positions from digest halves h1 and h2.
+func NewProbes(h1, h2, m uint64) Probes {
+	return Probes{
+		x: h1,
+		y: h2,
+		m: m,
+	}
+}
+
+// Next returns the current position and advances the sequence.
+func (p *Probes) Next() uint64 {
+	index, _ := bits.Mul64(p.x, p.m)
+	p.i++
+	p.x += p.y
+	p.y += p.i
+	return index
+}
@@ -0,0 +1,44 @@
+package hash
+
+import "testing"
+
+func TestProbes(t *testing.T) {
+	tests := []struct {
+		name string
+		m    uint64
+	}{
+		{"one bit", 1},
+		{"small", 16},
+		{"beyond 32 bits", 1 << 40},
+		{"maximum", ^uint64(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProbes(0x9e3779b97f4a7c15, 0xbf58476d1ce4e5b9, tt.m)
+			var above32 bool
+			for i := 0; i < 64; i++ {
+				index := p.Next()
+				if index >= tt.m {
+					t.Fatalf("Next() = %d, want < %d", index, tt.m)
+				}
+				above32 = above32 || index > 1<<32
+			}
+			if tt.m > 1<<33 && !above32 {
+				t.Errorf("no index above 2^32 for m = %d", tt.m)
+			}
+		})
+	}
+}
+
+// TestProbes_Positions pins the positions of one digest. They decide which
+// bits every serialized filter sets, so a change here is a format change.
+func TestProbes_Positions(t *testing.T) {
+	p := NewProbes(0x9e3779b97f4a7c15, 0xbf58476d1ce4e5b9, 1000)
+	want := []uint64{618, 365, 112, 860, 607, 355}
+	for i, w := range want {
+		if got := p.Next(); got != w {
+			t.Fatalf("Next() #%d = %d, want %d", i, got, w)
+		}
+	}
+}
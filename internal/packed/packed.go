@@ -0,0 +1,79 @@
+package packed
+
+// Array is a sequence of fixed-width unsigned integers packed into 64-bit
+// words; a value may straddle two words. Like slice indexing, accessing an
+// index at or beyond Len panics.
+type Array struct {
+	words  []uint64
+	length uint64
+	width  uint8
+	mask   uint64
+}
+
+// NewArray returns a zeroed array of length values, each width bits wide.
+// The width must be in [1, 64].
+func NewArray(length uint64, width uint8) *Array {
+	if width == 0 || width > 64 {
+		panic("width must be in range [1, 64]")
+	}
+
+	return &Array{
+		words:  make([]uint64, (length*uint64(width)+63)/64),
+		length: length,
+		width:  width,
+		mask:   ^uint64(0) >> (64 - width),
+	}
+}
+
+// Len returns the number of values.
+func (a *Array) Len() uint64 {
+	return a.length
+}
+
+// Width returns the width of each value in bits.
+func (a *Array) Width() uint8 {
+	return a.width
+}
+
+// Max returns the largest value that fits the width.
+func (a *Array) Max() uint64 {
+	return a.mask
+}
+
+// Words returns the backing words.
+func (a *Array) Words() []uint64 {
+	return a.words
+}
+
+func (a *Array) check(index uint64) {
+	if index >= a.length {
+		panic("packed: index out of range")
+	}
+}
+
+// Get returns the value at index.
+func (a *Array) Get(index uint64) uint64 {
+	a.check(index)
+	bit := index * uint64(a.width)
+	word, offset := bit/64, bit%64
+
+	v := a.words[word] >> offset
+	if offset+uint64(a.width) > 64 {
+		v |= a.words[word+1] << (64 - offset)
+	}
+	return v & a.mask
+}
+
+// Set stores value, truncated to the width, at index.
+func (a *Array) Set(index, value uint64) {
+	a.check(index)
+	bit := index * uint64(a.width)
+	word, offset := bit/64, bit%64
+	value &= a.mask
+
+	a.words[word] = a.words[word]&^(a.mask<<offset) | value<<offset
+	if offset+uint64(a.width) > 64 {
+		shift := 64 - offset
+		a.words[word+1] = a.words[word+1]&^(a.mask>>shift) | value>>shift
+	}
+}
@@ -0,0 +1,106 @@
+package packed
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestArrayNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		length    uint64
+		width     uint8
+		wantWords int
+		wantMax   uint64
+	}{
+		{"nibbles", 16, 4, 1, 15},
+		{"nibbles spill", 17, 4, 2, 15},
+		{"odd width", 10, 7, 2, 127},
+		{"single bits", 100, 1, 2, 1},
+		{"full words", 3, 64, 3, ^uint64(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewArray(tt.length, tt.width)
+			if a.Len() != tt.length || a.Width() != tt.width {
+				t.Errorf("Len(), Width() = %d, %d, want %d, %d", a.Len(), a.Width(), tt.length, tt.width)
+			}
+			if len(a.Words()) != tt.wantWords {
+				t.Errorf("len(Words()) = %d, want %d", len(a.Words()), tt.wantWords)
+			}
+			if a.Max() != tt.wantMax {
+				t.Errorf("Max() = %d, want %d", a.Max(), tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestArrayInvalidWidth(t *testing.T) {
+	for _, width := range []uint8{0, 65} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewArray(width %d) did not panic, want panic", width)
+				}
+			}()
+			NewArray(8, width)
+		}()
+	}
+}
+
+func TestArrayGetSet(t *testing.T) {
+	for _, width := range []uint8{1, 3, 4, 7, 13, 32, 63, 64} {
+		a := NewArray(257, width)
+		want := make([]uint64, a.Len())
+		rng := rand.New(rand.NewPCG(1, uint64(width)))
+
+		for round := 0; round < 4; round++ {
+			for i := range want {
+				want[i] = rng.Uint64() & a.Max()
+				a.Set(uint64(i), want[i])
+			}
+			for i := range want {
+				if got := a.Get(uint64(i)); got != want[i] {
+					t.Fatalf("width %d: Get(%d) = %d, want %d", width, i, got, want[i])
+				}
+			}
+		}
+	}
+}
+
+func TestArraySetTruncates(t *testing.T) {
+	a := NewArray(3, 4)
+	a.Set(1, 0xff)
+	if got := a.Get(1); got != 0xf {
+		t.Errorf("Get(1) = %#x, want 0xf", got)
+	}
+	if a.Get(0) != 0 || a.Get(2) != 0 {
+		t.Error("Set() wrote into neighbouring values")
+	}
+}
+
+func TestArrayOutOfRange(t *testing.T) {
+	a := NewArray(4, 4)
+	for name, fn := range map[string]func(){
+		"Get": func() { a.Get(4) },
+		"Set": func() { a.Set(4, 1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s(4) did not panic, want panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func BenchmarkArrayGetSet(b *testing.B) {
+	a := NewArray(1<<16, 4)
+	for i := 0; i < b.N; i++ {
+		index := uint64(i) & (1<<16 - 1)
+		a.Set(index, a.Get(index)+1)
+	}
+}
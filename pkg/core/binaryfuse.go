@@ -0,0 +1,218 @@
+package core
+
+import (
+	"alex/bvs/internal/fuse"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"slices"
+)
+
+// Fingerprint is the fingerprint type of a BinaryFuseFilter. uint8 gives a
+// false-positive rate of about 0.39% at roughly 9 bits per key, uint16 about
+// 0.0015% at roughly 18.
+type Fingerprint interface {
+	uint8 | uint16
+}
+
+// BinaryFuseFilter is a static filter built once from a known set of keys.
+// Each key maps to three slots in consecutive segments of a fingerprint
+// array, filled so that the XOR of a key's slots is its fingerprint. It uses
+// about 13% more space than the information-theoretic minimum, against 44%
+// for a BloomFilter, and a lookup reads exactly three slots.
+// Keys cannot be added after construction.
+// see Graf & Lemire, "Binary Fuse Filters: Fast and Smaller Than Xor Filters"
+// A BinaryFuseFilter is not safe for concurrent use.
+type BinaryFuseFilter[T any, F Fingerprint] struct {
+	keyHasher[T]
+	layout       fuse.Layout
+	seed         uint64
+	fingerprints []F
+	count        uint64
+}
+
+// NewBinaryFuse builds a binary fuse filter holding keys. Only the hashing
+// options apply: WithEncoder, WithHashFamily, WithKey, WithSecretKey and
+// WithFixedKey.
+// It returns an error wrapping ErrDuplicateKey if a key occurs twice.
+func NewBinaryFuse[T any, F Fingerprint](keys []T, opts ...Option) (*BinaryFuseFilter[T, F], error) {
+	return NewBinaryFuseSeq[T, F](slices.Values(keys), opts...)
+}
+
+// NewBinaryFuseSeq is like NewBinaryFuse but takes the keys from a sequence,
+// which is consumed once.
+func NewBinaryFuseSeq[T any, F Fingerprint](keys iter.Seq[T], opts ...Option) (*BinaryFuseFilter[T, F], error) {
+	cfg := optionsConfig(opts)
+	kh, err := keyHasherFor[T](cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []uint64
+	for key := range keys {
+		h1, _ := kh.sum(key)
+		hashes = append(hashes, h1)
+	}
+	layout, seed, steps, err := fuse.Build(hashes)
+	if err != nil {
+		return nil, buildError(hashes, err)
+	}
+
+	fingerprints := make([]F, layout.ArrayLength)
+	for _, s := range steps {
+		ps := layout.Positions(s.Hash)
+		fingerprints[s.Slot] = fingerprint[F](s.Hash) ^ fingerprints[ps[0]] ^ fingerprints[ps[1]] ^ fingerprints[ps[2]]
+	}
+
+	return &BinaryFuseFilter[T, F]{
+		keyHasher:    kh,
+		layout:       layout,
+		seed:         seed,
+		fingerprints: fingerprints,
+		count:        uint64(len(hashes)),
+	}, nil
+}
+
+// buildError maps an error from fuse.Build to the package's sentinel errors,
+// naming the positions of the first duplicate key.
+func buildError(hashes []uint64, err error) error {
+	switch {
+	case errors.Is(err, fuse.ErrDuplicate):
+		seen := make(map[uint64]int, len(hashes))
+		for i, h := range hashes {
+			if j, ok := seen[h]; ok {
+				return fmt.Errorf("%w: keys %d and %d", ErrDuplicateKey, j, i)
+			}
+			seen[h] = i
+		}
+		return ErrDuplicateKey
+	case errors.Is(err, fuse.ErrTooLarge):
+		return fmt.Errorf("%w: %d keys", ErrFilterTooLarge, len(hashes))
+	default:
+		return fmt.Errorf("%w: %w", ErrConstructionFailed, err)
+	}
+}
+
+func fingerprint[F Fingerprint](h uint64) F {
+	return F(h ^ h>>32)
+}
+
+// fingerprintBits returns the width of F in bits.
+func fingerprintBits[F Fingerprint]() int {
+	var zero F
+	if _, ok := any(zero).(uint8); ok {
+		return 8
+	}
+	return 16
+}
+
+// fuseLayout returns the serialized layout for fingerprints of type F.
+func fuseLayout[F Fingerprint]() Layout {
+	if fingerprintBits[F]() == 8 {
+		return layoutBinaryFuse8
+	}
+	return layoutBinaryFuse16
+}
+
+// Contains checks if an element might be in the filter.
+// Returns true if the element might be present (with possible false positives).
+// Returns false if the element is definitely not present.
+func (bf *BinaryFuseFilter[T, F]) Contains(data T) bool {
+	h1, _ := bf.sum(data)
+	h := fuse.Mix(h1, bf.seed)
+	ps := bf.layout.Positions(h)
+	return fingerprint[F](h) == bf.fingerprints[ps[0]]^bf.fingerprints[ps[1]]^bf.fingerprints[ps[2]]
+}
+
+// Len returns the number of keys the filter was built from.
+func (bf *BinaryFuseFilter[T, F]) Len() uint64 {
+	return bf.count
+}
+
+// Size returns the total bit size of the fingerprint array.
+func (bf *BinaryFuseFilter[T, F]) Size() uint64 {
+	return uint64(len(bf.fingerprints) * fingerprintBits[F]())
+}
+
+// FalsePositiveRate returns the chance that a key not in the set matches
+// its three slots by accident, 2^-bits for bits-wide fingerprints.
+func (bf *BinaryFuseFilter[T, F]) FalsePositiveRate() float64 {
+	return math.Ldexp(1, -fingerprintBits[F]())
+}
+
+func (bf *BinaryFuseFilter[T, F]) header() header {
+	return header{
+		layout:   fuseLayout[F](),
+		encoder:  bf.enc.ID(),
+		family:   bf.family.Name(),
+		key:      bf.key,
+		k:        3,
+		bits:     64 + bf.Size(),
+		count:    bf.count,
+		capacity: bf.count,
+		fpRate:   bf.FalsePositiveRate(),
+	}
+}
+
+// WriteTo writes the filter to w in the serialized format, with the binary
+// fuse layout matching its fingerprint width.
+func (bf *BinaryFuseFilter[T, F]) WriteTo(w io.Writer) (int64, error) {
+	payload := binary.LittleEndian.AppendUint64(nil, bf.seed)
+	for _, f := range bf.fingerprints {
+		if fingerprintBits[F]() == 8 {
+			payload = append(payload, uint8(f))
+		} else {
+			payload = binary.LittleEndian.AppendUint16(payload, uint16(f))
+		}
+	}
+	return writeFilter(w, bf.header(), payload)
+}
+
+// ReadFrom replaces the filter with one read from r in the serialized format.
+// The encoder is chosen as for BloomFilter.ReadFrom.
+func (bf *BinaryFuseFilter[T, F]) ReadFrom(r io.Reader) (int64, error) {
+	d, n, err := readFilter(r, fuseLayout[F](), bf.enc)
+	if err != nil {
+		return n, err
+	}
+	if d.k != 3 || d.count > math.MaxUint32/2 {
+		return n, fmt.Errorf("%w: hash count %d for %d keys", ErrInvalidFormat, d.k, d.count)
+	}
+	layout := fuse.NewLayout(uint32(d.count))
+	width := fingerprintBits[F]() / 8
+	if len(d.payload) != 8+int(layout.ArrayLength)*width {
+		return n, fmt.Errorf("%w: %d payload bytes for %d keys", ErrInvalidFormat, len(d.payload), d.count)
+	}
+
+	fingerprints := make([]F, layout.ArrayLength)
+	data := d.payload[8:]
+	for i := range fingerprints {
+		if width == 1 {
+			fingerprints[i] = F(data[i])
+		} else {
+			fingerprints[i] = F(binary.LittleEndian.Uint16(data[2*i:]))
+		}
+	}
+
+	*bf = BinaryFuseFilter[T, F]{
+		keyHasher:    d.keyHasher,
+		layout:       layout,
+		seed:         binary.LittleEndian.Uint64(d.payload),
+		fingerprints: fingerprints,
+		count:        d.count,
+	}
+	return n, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (bf *BinaryFuseFilter[T, F]) MarshalBinary() ([]byte, error) {
+	return marshal(72+len(bf.family.Name())+int(bf.Size()/8), bf.WriteTo)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. See ReadFrom.
+func (bf *BinaryFuseFilter[T, F]) UnmarshalBinary(data []byte) error {
+	return unmarshal(data, bf.ReadFrom)
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"alex/bvs/pkg/hash"
+	"errors"
+	"maps"
+	"math"
+	"testing"
+)
+
+func TestBinaryFuse8(t *testing.T) {
+	const n = 50000
+	keys := make([]int, n)
+	for i := range keys {
+		keys[i] = i
+	}
+	f, err := NewBinaryFuse[int, uint8](keys)
+	if err != nil {
+		t.Fatalf("NewBinaryFuse() error = %v", err)
+	}
+
+	if f.Len() != n {
+		t.Errorf("Len() = %d, want %d", f.Len(), n)
+	}
+	if bpk := float64(f.Size()) / n; bpk > 10 {
+		t.Errorf("bits per key = %g, want at most 10", bpk)
+	}
+	for _, key := range keys {
+		if !f.Contains(key) {
+			t.Fatalf("Contains(%d) = false, want true", key)
+		}
+	}
+
+	var fp int
+	for i := n; i < 11*n; i++ {
+		if f.Contains(i) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / (10 * n); math.Abs(rate-f.FalsePositiveRate()) > 0.001 {
+		t.Errorf("false-positive rate = %g, want about %g", rate, f.FalsePositiveRate())
+	}
+}
+
+func TestBinaryFuse16_Seq(t *testing.T) {
+	words := map[string]bool{"alpha": true, "beta": true, "gamma": true, "delta": true}
+	f, err := NewBinaryFuseSeq[string, uint16](maps.Keys(words))
+	if err != nil {
+		t.Fatalf("NewBinaryFuseSeq() error = %v", err)
+	}
+	for w := range words {
+		if !f.Contains(w) {
+			t.Errorf("Contains(%q) = false, want true", w)
+		}
+	}
+	if f.Contains("epsilon") {
+		t.Error("Contains(epsilon) = true, want false")
+	}
+	if f.FalsePositiveRate() != 1.0/65536 {
+		t.Errorf("FalsePositiveRate() = %g, want %g", f.FalsePositiveRate(), 1.0/65536)
+	}
+}
+
+func TestBinaryFuse_Small(t *testing.T) {
+	for n := 0; n < 20; n++ {
+		keys := make([]int, n)
+		for i := range keys {
+			keys[i] = i * 7
+		}
+		f, err := NewBinaryFuse[int, uint16](keys)
+		if err != nil {
+			t.Fatalf("n=%d: NewBinaryFuse() error = %v", n, err)
+		}
+		for _, key := range keys {
+			if !f.Contains(key) {
+				t.Fatalf("n=%d: Contains(%d) = false, want true", n, key)
+			}
+		}
+	}
+}
+
+func TestBinaryFuse_DuplicateKey(t *testing.T) {
+	_, err := NewBinaryFuse[string, uint8]([]string{"a", "b", "c", "b"})
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("NewBinaryFuse() error = %v, want %v", err, ErrDuplicateKey)
+	}
+	if want := "duplicate key: keys 1 and 3"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
+
+func TestBinaryFuse_MarshalRoundTrip(t *testing.T) {
+	keys := []string{"one", "two", "three", "four", "five"}
+	f := Must(NewBinaryFuse[string, uint16](keys, WithSecretKey()))
+
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	var g BinaryFuseFilter[string, uint16]
+	if err := g.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if g.Len() != f.Len() || g.Size() != f.Size() || g.Key() != f.Key() {
+		t.Errorf("round trip changed parameters: Len %d, Size %d, want %d, %d", g.Len(), g.Size(), f.Len(), f.Size())
+	}
+	for _, key := range keys {
+		if !g.Contains(key) {
+			t.Errorf("Contains(%q) = false after round trip, want true", key)
+		}
+	}
+
+	var narrow BinaryFuseFilter[string, uint8]
+	if err := narrow.UnmarshalBinary(data); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("8-bit UnmarshalBinary(16-bit) error = %v, want %v", err, ErrInvalidFormat)
+	}
+	if _, err := UnmarshalFilter[string](data); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("UnmarshalFilter(binary fuse) error = %v, want %v", err, ErrInvalidFormat)
+	}
+	data[len(data)-5] ^= 1
+	if err := g.UnmarshalBinary(data); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("UnmarshalBinary(corrupted) error = %v, want %v", err, ErrChecksumMismatch)
+	}
+}
+
+func BenchmarkBinaryFuseContains(b *testing.B) {
+	keys := make([]int, 1<<20)
+	for i := range keys {
+		keys[i] = i
+	}
+	f := Must(NewBinaryFuse[int, uint8](keys, WithHashFamily(hash.XXHash64)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.Contains(i)
+	}
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"alex/bvs/pkg/hash"
+	"fmt"
+	"testing"
+)
+
+func TestBlockedBloomFilter_New(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		wantSize uint64
+	}{
+		{"one block", Config{Bits: 100}, 512},
+		{"exact blocks", Config{Bits: 1024}, 1024},
+		{"rounded up", Config{Bits: 1025}, 1536},
+		{"from estimates", Config{Capacity: 1000, FalsePositiveRate: 0.01}, 9728},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewBlocked[int](tt.cfg)
+			if err != nil {
+				t.Fatalf("NewBlocked() error = %v", err)
+			}
+			if f.Size() != tt.wantSize {
+				t.Errorf("Size() = %d, want %d", f.Size(), tt.wantSize)
+			}
+		})
+	}
+
+	if _, err := NewBlocked[int](Config{}); err == nil {
+		t.Error("NewBlocked() with empty config error = nil, want error")
+	}
+}
+
+func TestBlockedBloomFilter_Membership(t *testing.T) {
+	const n = 10000
+	f := Must(NewBlocked[int](Config{Capacity: n, FalsePositiveRate: 0.01}))
+	for i := 0; i < n; i++ {
+		if err := f.Insert(i); err != nil {
+			t.Fatalf("Insert(%d) error = %v", i, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false after insert, want true", i)
+		}
+	}
+
+	falsePositives := 0
+	const probes = 50000
+	for i := n; i < n+probes; i++ {
+		if f.Contains(i) {
+			falsePositives++
+		}
+	}
+	// Blocking costs some accuracy; allow twice the target rate.
+	if got := float64(falsePositives) / probes; got > 0.02 {
+		t.Errorf("false-positive rate = %g, want at most 0.02", got)
+	}
+
+	s := f.Stats()
+	if s.Inserted < n*98/100 || s.Inserted > n || s.BitsSet == 0 || s.BitsSet > n*uint64(f.HashCount()) {
+		t.Errorf("Stats() = %+v", s)
+	}
+}
+
+func TestBlockedBloomFilter_SingleBlock(t *testing.T) {
+	f := Must(NewBlocked[string](Config{Bits: 1 << 16}))
+	f.Insert("key")
+
+	touched := 0
+	for b := uint64(0); b < f.blocks; b++ {
+		for _, w := range f.words[b*blockWords : (b+1)*blockWords] {
+			if w != 0 {
+				touched++
+				break
+			}
+		}
+	}
+	if touched != 1 {
+		t.Errorf("Insert() touched %d blocks, want 1", touched)
+	}
+}
+
+// Benchmarks compare lookups against the classic layout at equal memory,
+// with filters larger than the CPU cache so every probe can miss.
+func BenchmarkBlockedVsClassic(b *testing.B) {
+	for _, bitsize := range []uint64{1 << 16, 1 << 27} {
+		n := bitsize / 10
+		classic := Must(New[uint64](Config{Bits: bitsize}, WithHashFamily(hash.XXHash64)))
+		blocked := Must(NewBlocked[uint64](Config{Bits: bitsize}, WithHashFamily(hash.XXHash64)))
+		for i := uint64(0); i < n; i++ {
+			classic.Insert(i)
+			blocked.Insert(i)
+		}
+
+		b.Run(fmt.Sprintf("classic/%dKiB/Contains", bitsize/8192), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				classic.Contains(uint64(i) * 0x9e3779b97f4a7c15)
+			}
+		})
+		b.Run(fmt.Sprintf("blocked/%dKiB/Contains", bitsize/8192), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				blocked.Contains(uint64(i) * 0x9e3779b97f4a7c15)
+			}
+		})
+		b.Run(fmt.Sprintf("classic/%dKiB/Insert", bitsize/8192), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				classic.Insert(uint64(i) * 0x9e3779b97f4a7c15)
+			}
+		})
+		b.Run(fmt.Sprintf("blocked/%dKiB/Insert", bitsize/8192), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				blocked.Insert(uint64(i) * 0x9e3779b97f4a7c15)
+			}
+		})
+	}
+}
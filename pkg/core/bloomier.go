@@ -0,0 +1,182 @@
+package core
+
+import (
+	"alex/bvs/internal/fuse"
+	"alex/bvs/internal/packed"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"math/bits"
+)
+
+// BloomierValue is the value type of a Bloomier.
+type BloomierValue interface {
+	~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// Bloomier is a static approximate map from keys to small values, built
+// once from a known set of entries. Get returns the stored value for every
+// key the map was built from and an arbitrary value for any other key; it
+// cannot tell the two apart, so pair it with a filter if that matters.
+// It is laid out like a BinaryFuseFilter whose slots hold values instead of
+// fingerprints, taking about 1.13*ValueBits bits per key whatever the size
+// of the keys.
+// see Chazelle et al., "The Bloomier Filter: An Efficient Data Structure for Static Support Lookup Tables"
+// A Bloomier is not safe for concurrent use.
+type Bloomier[K any, V BloomierValue] struct {
+	keyHasher[K]
+	layout fuse.Layout
+	seed   uint64
+	values *packed.Array
+	count  uint64
+}
+
+// valueBits returns the width of V in bits.
+func valueBits[V BloomierValue]() uint8 {
+	return uint8(bits.Len64(uint64(^V(0))))
+}
+
+// NewBloomier builds a Bloomier mapping each key of entries to its value;
+// iterate a map with maps.All. Values must fit the width set by
+// WithValueBits. Only that option and the hashing options apply:
+// WithEncoder, WithHashFamily, WithKey, WithSecretKey and WithFixedKey.
+// It returns an error wrapping ErrDuplicateKey if a key occurs twice,
+// ErrValueTooWide if a value does not fit, or ErrConstructionFailed if the
+// entries cannot be laid out.
+func NewBloomier[K any, V BloomierValue](entries iter.Seq2[K, V], opts ...Option) (*Bloomier[K, V], error) {
+	cfg := optionsConfig(opts)
+	width := cfg.valueBits
+	if width == 0 {
+		width = valueBits[V]()
+	}
+	if width > valueBits[V]() {
+		return nil, fmt.Errorf("%w: value width %d exceeds %d", ErrValueTooWide, width, valueBits[V]())
+	}
+	kh, err := keyHasherFor[K](cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes, vals []uint64
+	for key, v := range entries {
+		if bits.Len64(uint64(v)) > int(width) {
+			return nil, fmt.Errorf("%w: entry %d has value %d, wider than %d bits", ErrValueTooWide, len(hashes), v, width)
+		}
+		h1, _ := kh.sum(key)
+		hashes = append(hashes, h1)
+		vals = append(vals, uint64(v))
+	}
+	layout, seed, steps, err := fuse.Build(hashes)
+	if err != nil {
+		return nil, buildError(hashes, err)
+	}
+
+	values := packed.NewArray(uint64(layout.ArrayLength), width)
+	for _, s := range steps {
+		ps := layout.Positions(s.Hash)
+		values.Set(uint64(s.Slot), vals[s.Key]^values.Get(uint64(ps[0]))^values.Get(uint64(ps[1]))^values.Get(uint64(ps[2])))
+	}
+
+	return &Bloomier[K, V]{
+		keyHasher: kh,
+		layout:    layout,
+		seed:      seed,
+		values:    values,
+		count:     uint64(len(hashes)),
+	}, nil
+}
+
+// Get returns the value stored for key if the map was built from it, and
+// an arbitrary value of at most ValueBits bits otherwise.
+func (bm *Bloomier[K, V]) Get(key K) V {
+	h1, _ := bm.sum(key)
+	ps := bm.layout.Positions(fuse.Mix(h1, bm.seed))
+	return V(bm.values.Get(uint64(ps[0])) ^ bm.values.Get(uint64(ps[1])) ^ bm.values.Get(uint64(ps[2])))
+}
+
+// Len returns the number of entries the map was built from.
+func (bm *Bloomier[K, V]) Len() uint64 {
+	return bm.count
+}
+
+// ValueBits returns the width in bits of each stored value.
+func (bm *Bloomier[K, V]) ValueBits() uint8 {
+	return bm.values.Width()
+}
+
+// Size returns the total bit size of the value array.
+func (bm *Bloomier[K, V]) Size() uint64 {
+	return uint64(len(bm.values.Words())) * 64
+}
+
+func (bm *Bloomier[K, V]) header() header {
+	return header{
+		layout:   layoutBloomier,
+		encoder:  bm.enc.ID(),
+		family:   bm.family.Name(),
+		key:      bm.key,
+		k:        3,
+		bits:     72 + bm.Size(),
+		count:    bm.count,
+		capacity: bm.count,
+	}
+}
+
+// WriteTo writes the map to w in the serialized format, with the Bloomier
+// layout.
+func (bm *Bloomier[K, V]) WriteTo(w io.Writer) (int64, error) {
+	payload := binary.LittleEndian.AppendUint64(nil, bm.seed)
+	payload = append(payload, bm.values.Width())
+	for _, word := range bm.values.Words() {
+		payload = binary.LittleEndian.AppendUint64(payload, word)
+	}
+	return writeFilter(w, bm.header(), payload)
+}
+
+// ReadFrom replaces the map with one read from r in the serialized format.
+// The encoder is chosen as for BloomFilter.ReadFrom.
+func (bm *Bloomier[K, V]) ReadFrom(r io.Reader) (int64, error) {
+	d, n, err := readFilter(r, layoutBloomier, bm.enc)
+	if err != nil {
+		return n, err
+	}
+	if d.k != 3 || d.count > math.MaxUint32/2 || len(d.payload) < 9 {
+		return n, fmt.Errorf("%w: hash count %d for %d keys", ErrInvalidFormat, d.k, d.count)
+	}
+	width := d.payload[8]
+	if width == 0 || width > valueBits[V]() {
+		return n, fmt.Errorf("%w: value width %d, want 1 to %d", ErrInvalidFormat, width, valueBits[V]())
+	}
+	layout := fuse.NewLayout(uint32(d.count))
+	// The count is checked against the payload before it sizes an allocation.
+	nwords := (uint64(layout.ArrayLength)*uint64(width) + 63) / 64
+	if uint64(len(d.payload)) != 9+8*nwords {
+		return n, fmt.Errorf("%w: %d payload bytes for %d keys", ErrInvalidFormat, len(d.payload), d.count)
+	}
+	values := packed.NewArray(uint64(layout.ArrayLength), width)
+	words := values.Words()
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint64(d.payload[9+8*i:])
+	}
+
+	*bm = Bloomier[K, V]{
+		keyHasher: d.keyHasher,
+		layout:    layout,
+		seed:      binary.LittleEndian.Uint64(d.payload),
+		values:    values,
+		count:     d.count,
+	}
+	return n, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (bm *Bloomier[K, V]) MarshalBinary() ([]byte, error) {
+	return marshal(73+len(bm.family.Name())+int(bm.Size()/8), bm.WriteTo)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. See ReadFrom.
+func (bm *Bloomier[K, V]) UnmarshalBinary(data []byte) error {
+	return unmarshal(data, bm.ReadFrom)
+}
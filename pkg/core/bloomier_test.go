@@ -0,0 +1,132 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"iter"
+	"maps"
+	"math/rand/v2"
+	"runtime"
+	"testing"
+)
+
+type tier uint8
+
+func TestBloomier(t *testing.T) {
+	const n = 20000
+	r := rand.New(rand.NewPCG(12, 13))
+	entries := make(map[int]tier, n)
+	for i := range n {
+		entries[i] = tier(r.IntN(8))
+	}
+
+	bm, err := NewBloomier(maps.All(entries), WithValueBits(3))
+	if err != nil {
+		t.Fatalf("NewBloomier() error = %v", err)
+	}
+	if bm.Len() != n || bm.ValueBits() != 3 {
+		t.Errorf("Len(), ValueBits() = %d, %d, want %d, 3", bm.Len(), bm.ValueBits(), n)
+	}
+	if bpk := float64(bm.Size()) / n; bpk > 3.75 {
+		t.Errorf("bits per key = %g, want at most 3.75", bpk)
+	}
+	for key, want := range entries {
+		if got := bm.Get(key); got != want {
+			t.Fatalf("Get(%d) = %d, want %d", key, got, want)
+		}
+	}
+	for key := n; key < 2*n; key++ {
+		if got := bm.Get(key); got > 7 {
+			t.Fatalf("Get(%d) = %d for an absent key, want at most 3 bits", key, got)
+		}
+	}
+}
+
+func TestBloomier_Small(t *testing.T) {
+	for n := 0; n < 20; n++ {
+		entries := make(map[string]uint16, n)
+		for i := range n {
+			entries[string(rune('a'+i))] = uint16(i * 1000)
+		}
+		bm, err := NewBloomier(maps.All(entries))
+		if err != nil {
+			t.Fatalf("n=%d: NewBloomier() error = %v", n, err)
+		}
+		for key, want := range entries {
+			if got := bm.Get(key); got != want {
+				t.Fatalf("n=%d: Get(%q) = %d, want %d", n, key, got, want)
+			}
+		}
+	}
+}
+
+// pairs returns a sequence over keys and values in order.
+func pairs[K, V any](keys []K, values []V) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for i, key := range keys {
+			if !yield(key, values[i]) {
+				return
+			}
+		}
+	}
+}
+
+func TestBloomier_Errors(t *testing.T) {
+	_, err := NewBloomier(pairs([]string{"a", "b", "a"}, []uint8{1, 2, 1}))
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("NewBloomier(duplicate) error = %v, want %v", err, ErrDuplicateKey)
+	}
+	_, err = NewBloomier(pairs([]string{"a", "b"}, []uint8{1, 9}), WithValueBits(3))
+	if !errors.Is(err, ErrValueTooWide) {
+		t.Errorf("NewBloomier(wide value) error = %v, want %v", err, ErrValueTooWide)
+	}
+	_, err = NewBloomier(pairs([]string{"a"}, []uint8{1}), WithValueBits(9))
+	if !errors.Is(err, ErrValueTooWide) {
+		t.Errorf("NewBloomier(9 bits of uint8) error = %v, want %v", err, ErrValueTooWide)
+	}
+}
+
+func TestBloomier_MarshalRoundTrip(t *testing.T) {
+	keys := []string{"free", "pro", "team", "enterprise"}
+	values := []tier{0, 1, 2, 5}
+	bm := Must(NewBloomier(pairs(keys, values), WithValueBits(3), WithSecretKey()))
+
+	data, err := bm.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	var got Bloomier[string, tier]
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if got.Len() != bm.Len() || got.ValueBits() != 3 || got.Key() != bm.Key() {
+		t.Errorf("round trip changed parameters: Len %d, ValueBits %d", got.Len(), got.ValueBits())
+	}
+	for i, key := range keys {
+		if v := got.Get(key); v != values[i] {
+			t.Errorf("Get(%q) = %d after round trip, want %d", key, v, values[i])
+		}
+	}
+
+	data[len(data)-5] ^= 1
+	if err := got.UnmarshalBinary(data); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("UnmarshalBinary(corrupted) error = %v, want %v", err, ErrChecksumMismatch)
+	}
+	huge := bytes.Clone(data)
+	binary.LittleEndian.PutUint64(huge[36+int(huge[7]):], 50_000_000)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if err := got.UnmarshalBinary(resum(huge)); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("UnmarshalBinary(huge count) error = %v, want %v", err, ErrInvalidFormat)
+	}
+	runtime.ReadMemStats(&after)
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+		t.Errorf("UnmarshalBinary(huge count) allocated %d bytes before rejecting it", alloc)
+	}
+
+	fuse, _ := Must(NewBinaryFuse[string, uint8](keys)).MarshalBinary()
+	if err := got.UnmarshalBinary(fuse); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("UnmarshalBinary(binary fuse) error = %v, want %v", err, ErrInvalidFormat)
+	}
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"alex/bvs/internal/bitset"
+	ihash "alex/bvs/internal/hash"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// ConcurrentBloomFilter is a bloom filter that is safe for concurrent use
+// without locks. Its bits are kept in 64-bit words set with atomic OR, so
+// concurrent inserts never lose each other's bits and a lookup racing with
+// an insert of the same key sees it either absent or present.
+// It sets the same bits as a BloomFilter built from the same Config and
+// shares its serialized form, so either can read what the other wrote.
+type ConcurrentBloomFilter[T any] struct {
+	keyHasher[T]
+	bs       *bitset.Atomic
+	k        uint32
+	elements atomic.Uint64
+	capacity uint64
+	fpRate   float64
+}
+
+// NewConcurrent creates a concurrent bloom filter described by cfg with
+// opts applied on top. It is sized like New. An encoder set with
+// WithEncoder must be safe for concurrent use, as the built-in ones are.
+func NewConcurrent[T any](cfg Config, opts ...Option) (*ConcurrentBloomFilter[T], error) {
+	p, enc, err := resolveFor[T](&cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConcurrentBloomFilter[T]{
+		keyHasher: newKeyHasher(p.family, p.key, enc),
+		bs:        bitset.NewAtomic(p.bits),
+		k:         p.k,
+		capacity:  p.capacity,
+		fpRate:    p.fpRate,
+	}, nil
+}
+
+func (cf *ConcurrentBloomFilter[T]) probes(data T) ihash.Probes {
+	h1, h2 := cf.sum(data)
+	return ihash.NewProbes(h1, h2, cf.bs.Size())
+}
+
+// Insert adds an element to the filter. It may be called concurrently with
+// any other method except ReadFrom and UnmarshalBinary.
+// If the element is already present (or appears to be due to hash collisions),
+// it is not counted again.
+func (cf *ConcurrentBloomFilter[T]) Insert(data T) error {
+	probes := cf.probes(data)
+
+	var added bool
+	for i := uint32(0); i < cf.k; i++ {
+		set, err := cf.bs.Set(probes.Next())
+		if err != nil {
+			return fmt.Errorf("inserting key: %w", err)
+		}
+		added = added || set
+	}
+	if added {
+		cf.elements.Add(1)
+	}
+	return nil
+}
+
+// Contains checks if an element might be in the filter.
+// Returns true if the element might be present (with possible false positives).
+// Returns false if the element is definitely not present.
+func (cf *ConcurrentBloomFilter[T]) Contains(data T) bool {
+	probes := cf.probes(data)
+
+	for i := uint32(0); i < cf.k; i++ {
+		if set, _ := cf.bs.IsSet(probes.Next()); !set {
+			return false
+		}
+	}
+	return true
+}
+
+// Size returns the total bit size of the filter.
+func (cf *ConcurrentBloomFilter[T]) Size() uint64 {
+	return cf.bs.Size()
+}
+
+// HashCount returns the number of hash functions the filter applies per element.
+func (cf *ConcurrentBloomFilter[T]) HashCount() uint32 {
+	return cf.k
+}
+
+// Capacity returns the number of elements the filter was sized for.
+func (cf *ConcurrentBloomFilter[T]) Capacity() uint64 {
+	return cf.capacity
+}
+
+// FalsePositiveRate returns the false-positive rate the filter was sized for,
+// expected once Capacity elements have been inserted.
+func (cf *ConcurrentBloomFilter[T]) FalsePositiveRate() float64 {
+	return cf.fpRate
+}
+
+// Stats reports the filter's current fill and accuracy. Inserts that run
+// concurrently may or may not be reflected.
+func (cf *ConcurrentBloomFilter[T]) Stats() Stats {
+	return newStats(cf.Size(), cf.bs.Count(), cf.k, cf.elements.Load())
+}
+
+func (cf *ConcurrentBloomFilter[T]) header() header {
+	return header{
+		layout:   LayoutClassic,
+		encoder:  cf.enc.ID(),
+		family:   cf.family.Name(),
+		key:      cf.key,
+		k:        cf.k,
+		bits:     cf.Size(),
+		count:    cf.elements.Load(),
+		capacity: cf.capacity,
+		fpRate:   cf.fpRate,
+	}
+}
+
+// WriteTo writes the filter to w in the serialized format of BloomFilter.
+// Inserts that run concurrently may or may not be included.
+func (cf *ConcurrentBloomFilter[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeFilter(w, cf.header(), cf.bs.Bytes())
+}
+
+// ReadFrom replaces the filter with one read from r in the serialized format
+// of BloomFilter. The encoder is chosen as for BloomFilter.ReadFrom. It must
+// not be called concurrently with other methods.
+func (cf *ConcurrentBloomFilter[T]) ReadFrom(r io.Reader) (int64, error) {
+	d, n, err := readFilter(r, LayoutClassic, cf.enc)
+	if err != nil {
+		return n, err
+	}
+	bs, err := bitset.AtomicFromBytes(d.payload, d.bits)
+	if err != nil {
+		return n, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+
+	cf.keyHasher = d.keyHasher
+	cf.bs = bs
+	cf.k = d.k
+	cf.elements.Store(d.count)
+	cf.capacity = d.capacity
+	cf.fpRate = d.fpRate
+	return n, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (cf *ConcurrentBloomFilter[T]) MarshalBinary() ([]byte, error) {
+	return marshal(64+len(cf.family.Name())+int((cf.Size()+7)/8), cf.WriteTo)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. See ReadFrom.
+func (cf *ConcurrentBloomFilter[T]) UnmarshalBinary(data []byte) error {
+	return unmarshal(data, cf.ReadFrom)
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentBloomFilter_Parallel(t *testing.T) {
+	const goroutines, perGoroutine = 8, 5000
+	f := Must(NewConcurrent[int](Config{Capacity: goroutines * perGoroutine, FalsePositiveRate: 0.01}))
+
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := g * perGoroutine; i < (g+1)*perGoroutine; i++ {
+				if err := f.Insert(i); err != nil {
+					t.Errorf("Insert(%d) error = %v", i, err)
+					return
+				}
+				if !f.Contains(i) {
+					t.Errorf("Contains(%d) = false right after Insert, want true", i)
+					return
+				}
+			}
+		}()
+		// Readers race with the inserts above.
+		go func() {
+			defer wg.Done()
+			for i := range perGoroutine {
+				f.Contains(i)
+			}
+			f.Stats()
+		}()
+	}
+	wg.Wait()
+
+	for i := range goroutines * perGoroutine {
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false after all inserts, want true", i)
+		}
+	}
+	s := f.Stats()
+	if s.Inserted < goroutines*perGoroutine*98/100 || s.Inserted > goroutines*perGoroutine {
+		t.Errorf("Stats().Inserted = %d, want about %d", s.Inserted, goroutines*perGoroutine)
+	}
+}
+
+func TestConcurrentBloomFilter_MatchesClassic(t *testing.T) {
+	cfg := Config{Capacity: 1000, FalsePositiveRate: 0.01, FixedKey: true}
+	// Either can stand in for the other as a Filter.
+	var cf, bf Filter[string] = Must(NewConcurrent[string](cfg)), Must(New[string](cfg))
+	for _, key := range []string{"a", "b", "c", "a", "dd", "eee"} {
+		cf.Insert(key)
+		bf.Insert(key)
+	}
+
+	got, err := cf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	want, _ := bf.MarshalBinary()
+	if !bytes.Equal(got, want) {
+		t.Error("MarshalBinary() differs from a BloomFilter with the same inserts")
+	}
+
+	f, err := UnmarshalFilter[string](got)
+	if err != nil {
+		t.Fatalf("UnmarshalFilter() error = %v", err)
+	}
+	if !f.Contains("dd") || f.Stats() != cf.Stats() {
+		t.Errorf("UnmarshalFilter() = %+v, want %+v", f.Stats(), cf.Stats())
+	}
+
+	var restored ConcurrentBloomFilter[string]
+	if err := restored.UnmarshalBinary(want); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if !restored.Contains("eee") || restored.Contains("ffff") || restored.Stats() != bf.Stats() {
+		t.Errorf("UnmarshalBinary() = %+v, want %+v", restored.Stats(), bf.Stats())
+	}
+	restored.Insert("ffff")
+	if !restored.Contains("ffff") {
+		t.Error("Contains() = false after Insert into an unmarshaled filter, want true")
+	}
+
+	want[len(want)-5] ^= 1
+	if err := restored.UnmarshalBinary(want); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("UnmarshalBinary(corrupted) error = %v, want %v", err, ErrChecksumMismatch)
+	}
+}
+
+// lockedBloomFilter is the mutex-guarded BloomFilter that
+// ConcurrentBloomFilter replaces, for comparison in benchmarks.
+type lockedBloomFilter struct {
+	mu sync.Mutex
+	bf *BloomFilter[int]
+}
+
+func (l *lockedBloomFilter) Insert(data int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.bf.Insert(data)
+}
+
+func (l *lockedBloomFilter) Contains(data int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.bf.Contains(data)
+}
+
+func BenchmarkConcurrentBloomFilter(b *testing.B) {
+	cfg := Config{Capacity: 1 << 20, FalsePositiveRate: 0.01}
+	filters := []struct {
+		name string
+		f    interface {
+			Insert(int) error
+			Contains(int) bool
+		}
+	}{
+		{"atomic", Must(NewConcurrent[int](cfg))},
+		{"mutex", &lockedBloomFilter{bf: Must(New[int](cfg))}},
+	}
+
+	for _, tt := range filters {
+		b.Run(tt.name+"/Insert", func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					tt.f.Insert(i)
+				}
+			})
+		})
+		b.Run(tt.name+"/Contains", func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					tt.f.Contains(i)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package core
+
+import (
+	"alex/bvs/pkg/hash"
+	"encoding"
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxHashCount bounds Config.HashCount and the derived hash count; beyond it
+// every insert touches more bits than any useful false-positive rate needs.
+const maxHashCount = 1024
+
+// Layout identifies how a bloom filter arranges its bits, and is recorded in
+// the serialized form. NewFilter builds either layout.
+type Layout uint8
+
+const (
+	// LayoutClassic lets every hash function address the whole bitset; see
+	// BloomFilter.
+	LayoutClassic Layout = iota
+	// LayoutPartitioned gives each hash function its own slice of the
+	// bitset; see PartitionedBloomFilter.
+	LayoutPartitioned
+
+	// The other serialized filter types record their own layouts, which
+	// NewFilter does not build.
+	layoutBinaryFuse8
+	layoutBinaryFuse16
+	layoutIBLT
+	layoutStrataEstimator
+	layoutBloomier
+)
+
+// Config describes a bloom filter. Size it either by Bits or by Capacity and
+// FalsePositiveRate; the fields left at zero are derived from the others.
+// Bloom filter variants with settings of their own embed it in their own
+// config, such as CountingConfig; filter types sized differently have a
+// config of their own and take the hashing settings as options.
+type Config struct {
+	// Bits is the filter size in bits. Zero derives it from Capacity and
+	// FalsePositiveRate.
+	Bits uint64
+	// HashCount is the number of bit positions per key. Zero derives the
+	// optimal count for Bits and Capacity.
+	HashCount uint32
+	// Capacity is the expected number of distinct keys. Zero with Bits set
+	// assumes 10 bits per key.
+	Capacity uint64
+	// FalsePositiveRate is the target rate at Capacity keys, in (0, 1).
+	// It cannot be combined with Bits.
+	FalsePositiveRate float64
+	// HashFamily names a registered hash family; empty selects hash.SipHash.
+	HashFamily string
+	// Key seeds the hasher. Nil draws a random key from crypto/rand, so
+	// bit positions cannot be predicted, unless FixedKey is set.
+	Key *hash.Key
+	// SecretKey asks for a random key. That is the default, so it only
+	// states the intent. It cannot be combined with Key or FixedKey.
+	SecretKey bool
+	// FixedKey seeds the hasher from a fixed public key, so filters built
+	// apart hash alike without exchanging a key. Anyone can then choose keys
+	// that collide, so use it only for trusted input. It cannot be combined
+	// with Key or SecretKey.
+	FixedKey bool
+	// Layout selects the filter type NewFilter builds. Zero selects
+	// LayoutClassic.
+	Layout Layout
+
+	encoder   any
+	now       func() time.Time
+	valueBits uint8
+}
+
+// params are the validated, fully derived parameters of a filter.
+type params struct {
+	bits     uint64
+	k        uint32
+	capacity uint64
+	fpRate   float64
+	family   hash.Family
+	key      hash.Key
+}
+
+// resolve validates c and derives the parameters it leaves at zero.
+func (c Config) resolve() (params, error) {
+	var p params
+
+	if c.FalsePositiveRate != 0 && !(c.FalsePositiveRate > 0 && c.FalsePositiveRate < 1) {
+		return p, fmt.Errorf("%w: %g", ErrInvalidFalsePositiveRate, c.FalsePositiveRate)
+	}
+	if c.HashCount > maxHashCount {
+		return p, fmt.Errorf("%w: %d exceeds %d", ErrInvalidHashCount, c.HashCount, maxHashCount)
+	}
+
+	switch {
+	case c.Bits != 0 && c.FalsePositiveRate != 0:
+		return p, fmt.Errorf("%w: Bits and FalsePositiveRate are mutually exclusive", ErrConflictingConfig)
+	case c.Bits != 0:
+		p.bits = c.Bits
+		p.capacity = c.Capacity
+		if p.capacity == 0 {
+			p.capacity = max(c.Bits/defaultBitsPerElement, 1)
+		}
+	case c.Capacity == 0 && c.FalsePositiveRate == 0:
+		return p, ErrInvalidSize
+	case c.Capacity == 0:
+		return p, ErrInvalidCapacity
+	case c.FalsePositiveRate == 0:
+		return p, ErrInvalidFalsePositiveRate
+	default:
+		m := optimalBits(c.Capacity, c.FalsePositiveRate)
+		if exceedsMaxBits(m) {
+			return p, fmt.Errorf("%w: %.0f bits for %d elements at p=%g", ErrFilterTooLarge, m, c.Capacity, c.FalsePositiveRate)
+		}
+		p.bits = uint64(max(m, 1))
+		p.capacity = c.Capacity
+	}
+	if p.bits > maxBits {
+		return p, fmt.Errorf("%w: %d bits", ErrFilterTooLarge, p.bits)
+	}
+
+	p.k = c.HashCount
+	if p.k == 0 {
+		// A filter far larger than its capacity would otherwise derive a
+		// hash count no serialized filter may carry.
+		p.k = min(optimalHashes(p.bits, p.capacity), maxHashCount)
+	}
+	p.fpRate = c.FalsePositiveRate
+	if p.fpRate == 0 || c.HashCount != 0 {
+		p.fpRate = falsePositiveRate(p.bits, p.k, p.capacity)
+	}
+
+	var err error
+	if p.family, err = familyFor(c); err != nil {
+		return p, err
+	}
+	if p.key, err = keyFor(c); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// resolveFor applies opts to cfg, then resolves it along with the encoder
+// for T. Constructors of every filter type go through it.
+func resolveFor[T any](cfg *Config, opts []Option) (params, Encoder[T], error) {
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	p, err := cfg.resolve()
+	if err != nil {
+		return p, nil, err
+	}
+	enc, err := encoderFor[T](*cfg)
+	if err != nil {
+		return p, nil, err
+	}
+	return p, enc, nil
+}
+
+// keyHasherFor resolves the hash family, key and encoder cfg selects for T.
+// Filter types that are not sized like a bloom filter build on it instead of
+// resolveFor.
+func keyHasherFor[T any](cfg Config) (keyHasher[T], error) {
+	family, err := familyFor(cfg)
+	if err != nil {
+		return keyHasher[T]{}, err
+	}
+	key, err := keyFor(cfg)
+	if err != nil {
+		return keyHasher[T]{}, err
+	}
+	enc, err := encoderFor[T](cfg)
+	if err != nil {
+		return keyHasher[T]{}, err
+	}
+	return newKeyHasher(family, key, enc), nil
+}
+
+// optionsConfig returns the Config opts set up, for filter types that take
+// only the hashing and type-specific options.
+func optionsConfig(opts []Option) Config {
+	var cfg Config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// New creates a bloom filter described by cfg with opts applied on top.
+// Invalid or conflicting settings are reported as errors wrapping the
+// package's sentinel errors.
+func New[T any](cfg Config, opts ...Option) (*BloomFilter[T], error) {
+	p, enc, err := resolveFor[T](&cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newBloomFilter(p, enc), nil
+}
+
+// Filter is the behaviour shared by BloomFilter and PartitionedBloomFilter,
+// so callers can switch between them with Config.Layout.
+type Filter[T any] interface {
+	Insert(data T) error
+	Contains(data T) bool
+	Size() uint64
+	HashCount() uint32
+	Capacity() uint64
+	FalsePositiveRate() float64
+	Stats() Stats
+	io.WriterTo
+	io.ReaderFrom
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// NewFilter creates the filter cfg.Layout selects, described by cfg with opts
+// applied on top.
+func NewFilter[T any](cfg Config, opts ...Option) (Filter[T], error) {
+	switch cfg.Layout {
+	case LayoutClassic:
+		return New[T](cfg, opts...)
+	case LayoutPartitioned:
+		return NewPartitioned[T](cfg, opts...)
+	default:
+		return nil, fmt.Errorf("%w: layout %d is not a bloom filter layout", ErrConflictingConfig, cfg.Layout)
+	}
+}
+
+// Must returns f, panicking if err is non-nil. It wraps constructors
+// for callers with known-good configuration.
+func Must[F any](f F, err error) F {
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"alex/bvs/pkg/hash"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestConfig_New(t *testing.T) {
+	key := hash.KeyFromSeeds(1, 2)
+
+	tests := []struct {
+		name          string
+		cfg           Config
+		wantErr       error
+		wantBits      uint64
+		wantHashCount uint32
+		wantCapacity  uint64
+	}{
+		{"bits only", Config{Bits: 1024}, nil, 1024, 7, 102},
+		{"bits and capacity", Config{Bits: 1024, Capacity: 50}, nil, 1024, 14, 50},
+		{"bits and hash count", Config{Bits: 1024, HashCount: 3}, nil, 1024, 3, 102},
+		{"capacity and rate", Config{Capacity: 1000, FalsePositiveRate: 0.01}, nil, 9586, 7, 1000},
+		{"derived hash count clamped", Config{Bits: 1 << 20, Capacity: 1}, nil, 1 << 20, maxHashCount, 1},
+		{"explicit hash count", Config{Capacity: 1000, FalsePositiveRate: 0.01, HashCount: 4}, nil, 9586, 4, 1000},
+		{"hash family", Config{Bits: 64, HashFamily: hash.XXHash64}, nil, 64, 7, 6},
+		{"key", Config{Bits: 64, Key: &key}, nil, 64, 7, 6},
+		{"empty", Config{}, ErrInvalidSize, 0, 0, 0},
+		{"rate without capacity", Config{FalsePositiveRate: 0.01}, ErrInvalidCapacity, 0, 0, 0},
+		{"capacity without rate", Config{Capacity: 1000}, ErrInvalidFalsePositiveRate, 0, 0, 0},
+		{"rate out of range", Config{Capacity: 1000, FalsePositiveRate: 1.5}, ErrInvalidFalsePositiveRate, 0, 0, 0},
+		{"bits and rate", Config{Bits: 1024, FalsePositiveRate: 0.01}, ErrConflictingConfig, 0, 0, 0},
+		{"too many hashes", Config{Bits: 1024, HashCount: maxHashCount + 1}, ErrInvalidHashCount, 0, 0, 0},
+		{"too large", Config{Capacity: 1 << 62, FalsePositiveRate: 1e-9}, ErrFilterTooLarge, 0, 0, 0},
+		{"bits past max", Config{Bits: maxBits + 1}, ErrFilterTooLarge, 0, 0, 0},
+		{"bits near 2^64", Config{Bits: math.MaxUint64 - 3}, ErrFilterTooLarge, 0, 0, 0},
+		{"unknown family", Config{Bits: 1024, HashFamily: "md5"}, ErrUnknownHashFamily, 0, 0, 0},
+		{"key and secret key", Config{Bits: 1024, Key: &key, SecretKey: true}, ErrConflictingConfig, 0, 0, 0},
+		{"key and fixed key", Config{Bits: 1024, Key: &key, FixedKey: true}, ErrConflictingConfig, 0, 0, 0},
+		{"secret and fixed key", Config{Bits: 1024, SecretKey: true, FixedKey: true}, ErrConflictingConfig, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New[string](tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("New() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if f.Size() != tt.wantBits {
+				t.Errorf("Size() = %d, want %d", f.Size(), tt.wantBits)
+			}
+			if f.HashCount() != tt.wantHashCount {
+				t.Errorf("HashCount() = %d, want %d", f.HashCount(), tt.wantHashCount)
+			}
+			if f.Capacity() != tt.wantCapacity {
+				t.Errorf("Capacity() = %d, want %d", f.Capacity(), tt.wantCapacity)
+			}
+		})
+	}
+}
+
+func TestConfig_MaxBits(t *testing.T) {
+	if _, err := (Config{Bits: maxBits}).resolve(); err != nil {
+		t.Errorf("resolve() at maxBits error = %v, want nil", err)
+	}
+	if _, err := (Config{Bits: maxBits + 1}).resolve(); !errors.Is(err, ErrFilterTooLarge) {
+		t.Errorf("resolve() past maxBits error = %v, want %v", err, ErrFilterTooLarge)
+	}
+	if exceedsMaxBits(float64(maxBits)/2) || !exceedsMaxBits(float64(maxBits)) {
+		t.Error("exceedsMaxBits() disagrees with maxBits")
+	}
+	// Rounding maxBits up to whole storage units must not wrap around.
+	for _, unit := range []uint64{8, 64, blockBits} {
+		if got := (maxBits + unit - 1) / unit * unit; got != maxBits {
+			t.Errorf("maxBits rounded up to a multiple of %d = %d, want %d", unit, got, maxBits)
+		}
+	}
+}
+
+func TestConfig_Options(t *testing.T) {
+	f, err := New[string](Config{Bits: 1024}, WithHashFamily(hash.Murmur3), WithKey(hash.KeyFromSeeds(3, 4)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if f.HashFamily() != hash.Murmur3 {
+		t.Errorf("HashFamily() = %q, want %q", f.HashFamily(), hash.Murmur3)
+	}
+	if f.Key() != hash.KeyFromSeeds(3, 4) {
+		t.Errorf("Key() = %v, want %v", f.Key(), hash.KeyFromSeeds(3, 4))
+	}
+}
+
+func TestMust(t *testing.T) {
+	t.Run("valid config", func(t *testing.T) {
+		f := Must(New[int](Config{Bits: 64}))
+		if f.Size() != 64 {
+			t.Errorf("Size() = %d, want 64", f.Size())
+		}
+	})
+
+	t.Run("invalid config panics", func(t *testing.T) {
+		defer func() {
+			r := recover()
+			if err, ok := r.(error); !ok || !errors.Is(err, ErrInvalidSize) {
+				t.Errorf("Must() panicked with %v, want %v", r, ErrInvalidSize)
+			}
+		}()
+		Must(New[int](Config{}))
+	})
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"alex/bvs/internal/bitset"
+	ihash "alex/bvs/internal/hash"
+	"alex/bvs/internal/packed"
+	"fmt"
+)
+
+// defaultCounterWidth gives 4-bit counters, which overflow with
+// negligible probability at the optimal hash count.
+// see Fan et al., "Summary Cache: A Scalable Wide-Area Web Cache Sharing Protocol"
+const defaultCounterWidth = 4
+
+// CountingBloomFilter is a bloom filter whose bits are replaced by small
+// counters, so elements can be removed as well as inserted.
+// A counter that reaches its maximum saturates: it is never incremented or
+// decremented again, which may keep removed elements looking present but
+// never loses one that is still inserted.
+// A CountingBloomFilter is not safe for concurrent use.
+type CountingBloomFilter[T any] struct {
+	keyHasher[T]
+	counters *packed.Array
+	k        uint32
+	elements uint64
+	capacity uint64
+	fpRate   float64
+}
+
+// CountingConfig describes a counting bloom filter. It is sized like a bloom
+// filter, with Bits as the number of counters.
+type CountingConfig struct {
+	Config
+	// CounterWidth is the width in bits of each counter, in [1, 32]. Zero
+	// selects 4.
+	CounterWidth uint8
+}
+
+// NewCounting creates a counting bloom filter described by cfg with opts
+// applied on top.
+func NewCounting[T any](cfg CountingConfig, opts ...Option) (*CountingBloomFilter[T], error) {
+	p, enc, err := resolveFor[T](&cfg.Config, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	width := cfg.CounterWidth
+	if width == 0 {
+		width = defaultCounterWidth
+	}
+	if width > 32 {
+		return nil, fmt.Errorf("%w: counter width %d exceeds 32", ErrInvalidWidth, width)
+	}
+	if p.bits > maxBits/uint64(width) {
+		return nil, fmt.Errorf("%w: %d counters of %d bits", ErrFilterTooLarge, p.bits, width)
+	}
+
+	return &CountingBloomFilter[T]{
+		keyHasher: newKeyHasher(p.family, p.key, enc),
+		counters:  packed.NewArray(p.bits, width),
+		k:         p.k,
+		capacity:  p.capacity,
+		fpRate:    p.fpRate,
+	}, nil
+}
+
+func (cf *CountingBloomFilter[T]) probes(data T) ihash.Probes {
+	h1, h2 := cf.sum(data)
+	return ihash.NewProbes(h1, h2, cf.counters.Len())
+}
+
+// Insert adds one occurrence of an element, incrementing its counters.
+// Unlike BloomFilter, repeated inserts are counted so that each can be
+// matched by a Remove.
+// It never fails: a full counter saturates instead. The error result keeps
+// its signature in line with BloomFilter.Insert.
+func (cf *CountingBloomFilter[T]) Insert(data T) error {
+	probes := cf.probes(data)
+	limit := cf.counters.Max()
+
+	cf.elements++
+	for i := uint32(0); i < cf.k; i++ {
+		index := probes.Next()
+		if c := cf.counters.Get(index); c < limit {
+			cf.counters.Set(index, c+1)
+		}
+	}
+	return nil
+}
+
+// Remove deletes one occurrence of an element, decrementing its counters.
+// It returns ErrNotPresent, leaving the filter unchanged, if the element is
+// definitely not in the filter. Removing an element that was never inserted
+// but is reported present by a false positive corrupts the filter.
+func (cf *CountingBloomFilter[T]) Remove(data T) error {
+	probes := cf.probes(data)
+	if !cf.containsProbes(probes) {
+		return ErrNotPresent
+	}
+
+	limit := cf.counters.Max()
+	cf.elements--
+	for i := uint32(0); i < cf.k; i++ {
+		index := probes.Next()
+		if c := cf.counters.Get(index); c < limit {
+			cf.counters.Set(index, c-1)
+		}
+	}
+	return nil
+}
+
+// Contains checks if an element might be in the filter.
+// Returns true if the element might be present (with possible false positives).
+// Returns false if the element is definitely not present.
+func (cf *CountingBloomFilter[T]) Contains(data T) bool {
+	return cf.containsProbes(cf.probes(data))
+}
+
+func (cf *CountingBloomFilter[T]) containsProbes(probes ihash.Probes) bool {
+	for i := uint32(0); i < cf.k; i++ {
+		if cf.counters.Get(probes.Next()) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns an upper bound on how many times an element was inserted:
+// the smallest of its counters. A saturated result means at least that many.
+func (cf *CountingBloomFilter[T]) Count(data T) uint64 {
+	probes := cf.probes(data)
+	count := cf.counters.Max()
+	for i := uint32(0); i < cf.k; i++ {
+		count = min(count, cf.counters.Get(probes.Next()))
+	}
+	return count
+}
+
+// ToBloomFilter returns a plain bloom filter with a bit set for every
+// non-zero counter. It answers Contains exactly like cf, takes a fraction of
+// the memory, and can be serialized for read-only distribution.
+func (cf *CountingBloomFilter[T]) ToBloomFilter() *BloomFilter[T] {
+	bs := bitset.NewBitset(cf.counters.Len())
+	for i := uint64(0); i < cf.counters.Len(); i++ {
+		if cf.counters.Get(i) != 0 {
+			bs.Set(i)
+		}
+	}
+
+	return &BloomFilter[T]{
+		keyHasher: newKeyHasher(cf.family, cf.key, cf.enc),
+		bs:        bs,
+		k:         cf.k,
+		elements:  cf.elements,
+		capacity:  cf.capacity,
+		fpRate:    cf.fpRate,
+	}
+}
+
+// Size returns the number of counters.
+func (cf *CountingBloomFilter[T]) Size() uint64 {
+	return cf.counters.Len()
+}
+
+// CounterWidth returns the width of each counter in bits.
+func (cf *CountingBloomFilter[T]) CounterWidth() uint8 {
+	return cf.counters.Width()
+}
+
+// HashCount returns the number of counters touched per element.
+func (cf *CountingBloomFilter[T]) HashCount() uint32 {
+	return cf.k
+}
+
+// Len returns the number of elements inserted and not yet removed.
+func (cf *CountingBloomFilter[T]) Len() uint64 {
+	return cf.elements
+}
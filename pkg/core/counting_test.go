@@ -0,0 +1,156 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCountingBloomFilter_New(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       CountingConfig
+		wantErr   error
+		wantWidth uint8
+	}{
+		{"default width", CountingConfig{Config: Config{Bits: 1024}}, nil, 4},
+		{"custom width", CountingConfig{Config: Config{Bits: 1024}, CounterWidth: 8}, nil, 8},
+		{"single bit", CountingConfig{Config: Config{Capacity: 100, FalsePositiveRate: 0.01}, CounterWidth: 1}, nil, 1},
+		{"width too large", CountingConfig{Config: Config{Bits: 1024}, CounterWidth: 33}, ErrInvalidWidth, 0},
+		{"counters too large", CountingConfig{Config: Config{Bits: maxBits / 2}, CounterWidth: 4}, ErrFilterTooLarge, 0},
+		{"invalid config", CountingConfig{}, ErrInvalidSize, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewCounting[string](tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewCounting() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && f.CounterWidth() != tt.wantWidth {
+				t.Errorf("CounterWidth() = %d, want %d", f.CounterWidth(), tt.wantWidth)
+			}
+		})
+	}
+}
+
+func TestCountingBloomFilter_InsertRemove(t *testing.T) {
+	f := Must(NewCounting[int](CountingConfig{Config: Config{Capacity: 1000, FalsePositiveRate: 0.01}}))
+	for i := 0; i < 1000; i++ {
+		if err := f.Insert(i); err != nil {
+			t.Fatalf("Insert(%d) error = %v", i, err)
+		}
+	}
+	for i := 0; i < 1000; i += 2 {
+		if err := f.Remove(i); err != nil {
+			t.Fatalf("Remove(%d) error = %v", i, err)
+		}
+	}
+
+	if f.Len() != 500 {
+		t.Errorf("Len() = %d, want 500", f.Len())
+	}
+	for i := 1; i < 1000; i += 2 {
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false for a kept element, want true", i)
+		}
+	}
+	present := 0
+	for i := 0; i < 1000; i += 2 {
+		if f.Contains(i) {
+			present++
+		}
+	}
+	if present > 25 {
+		t.Errorf("%d of 500 removed elements still present", present)
+	}
+}
+
+func TestCountingBloomFilter_RemoveAbsent(t *testing.T) {
+	f := Must(NewCounting[string](CountingConfig{Config: Config{Bits: 1024}}))
+	f.Insert("kept")
+	before := f.Count("kept")
+
+	if err := f.Remove("never inserted"); !errors.Is(err, ErrNotPresent) {
+		t.Errorf("Remove() error = %v, want %v", err, ErrNotPresent)
+	}
+	if f.Count("kept") != before || f.Len() != 1 {
+		t.Error("failed Remove() changed the filter")
+	}
+}
+
+func TestCountingBloomFilter_Count(t *testing.T) {
+	f := Must(NewCounting[string](CountingConfig{Config: Config{Bits: 4096}}))
+	for i := 0; i < 3; i++ {
+		f.Insert("thrice")
+	}
+	f.Insert("once")
+
+	tests := []struct {
+		key  string
+		want uint64
+	}{
+		{"thrice", 3},
+		{"once", 1},
+		{"never", 0},
+	}
+	for _, tt := range tests {
+		if got := f.Count(tt.key); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.key, got, tt.want)
+		}
+	}
+
+	f.Remove("thrice")
+	if got := f.Count("thrice"); got != 2 {
+		t.Errorf("Count(thrice) after Remove = %d, want 2", got)
+	}
+}
+
+func TestCountingBloomFilter_Saturation(t *testing.T) {
+	f := Must(NewCounting[string](CountingConfig{Config: Config{Bits: 1024}, CounterWidth: 2}))
+	for i := 0; i < 10; i++ {
+		f.Insert("hot")
+	}
+	if got := f.Count("hot"); got != 3 {
+		t.Errorf("Count(hot) = %d, want saturated 3", got)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := f.Remove("hot"); err != nil {
+			t.Fatalf("Remove(hot) #%d error = %v", i, err)
+		}
+	}
+	if !f.Contains("hot") {
+		t.Error("saturated counters were decremented, want them to stick")
+	}
+}
+
+func TestCountingBloomFilter_ToBloomFilter(t *testing.T) {
+	f := Must(NewCounting[int](CountingConfig{Config: Config{Capacity: 500, FalsePositiveRate: 0.01}}))
+	for i := 0; i < 500; i++ {
+		f.Insert(i)
+	}
+	for i := 0; i < 100; i++ {
+		f.Remove(i)
+	}
+
+	bf := f.ToBloomFilter()
+	if bf.Size() != f.Size() || bf.HashCount() != f.HashCount() || bf.Key() != f.Key() {
+		t.Errorf("ToBloomFilter() parameters differ: %+v", bf.Stats())
+	}
+	for i := 0; i < 1000; i++ {
+		if bf.Contains(i) != f.Contains(i) {
+			t.Fatalf("Contains(%d) = %v on bloom filter, %v on counting filter", i, bf.Contains(i), f.Contains(i))
+		}
+	}
+	if _, err := bf.MarshalBinary(); err != nil {
+		t.Errorf("MarshalBinary() error = %v", err)
+	}
+}
+
+func BenchmarkCountingBloomFilter_InsertRemove(b *testing.B) {
+	f := Must(NewCounting[int](CountingConfig{Config: Config{Bits: 8192}}))
+	for i := 0; i < b.N; i++ {
+		f.Insert(i)
+		f.Remove(i)
+	}
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	ihash "alex/bvs/internal/hash"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+const defaultDelta = 0.01
+
+// CountMinSketch estimates how many times each key was added. It keeps
+// Depth rows of Width counters; a key adds to one counter per row and its
+// estimate is the smallest of them. Collisions only ever inflate counters, so
+// an estimate is never below the true count, and with probability 1-Delta it
+// exceeds it by at most Epsilon times the total count added.
+// In conservative-update mode Add raises only the counters below the key's
+// new estimate, which leaves the guarantee intact and tightens estimates
+// considerably on skewed streams.
+// see Cormode & Muthukrishnan, "An Improved Data Stream Summary: The Count-Min Sketch and its Applications"
+// and Estan & Varghese, "New Directions in Traffic Measurement and Accounting"
+// A CountMinSketch is not safe for concurrent use.
+type CountMinSketch[T any] struct {
+	keyHasher[T]
+	counters     []uint64
+	width        uint64
+	depth        uint64
+	total        uint64
+	conservative bool
+}
+
+// CountMinConfig describes a count-min sketch. Its width is ceil(e/Epsilon)
+// and its depth ceil(ln(1/Delta)).
+type CountMinConfig struct {
+	// Epsilon is the error bound as a fraction of the total count added, in
+	// (0, 1). It must be set.
+	Epsilon float64
+	// Delta is the probability that an estimate exceeds the Epsilon bound,
+	// in (0, 1). Zero selects 0.01.
+	Delta float64
+	// ConservativeUpdate raises only the counters that are below a key's new
+	// estimate, which reduces overestimation.
+	ConservativeUpdate bool
+}
+
+// NewCountMin creates a count-min sketch described by cfg. Only the hashing
+// options apply: WithEncoder, WithHashFamily, WithKey, WithSecretKey and
+// WithFixedKey.
+func NewCountMin[T any](cfg CountMinConfig, opts ...Option) (*CountMinSketch[T], error) {
+	delta := cfg.Delta
+	if delta == 0 {
+		delta = defaultDelta
+	}
+	if !(cfg.Epsilon > 0 && cfg.Epsilon < 1) || !(delta > 0 && delta < 1) {
+		return nil, fmt.Errorf("%w: epsilon %g, delta %g", ErrInvalidAccuracy, cfg.Epsilon, delta)
+	}
+
+	width := math.Ceil(math.E / cfg.Epsilon)
+	depth := math.Ceil(math.Log(1 / delta))
+	if exceedsMaxBits(width * depth * 64) {
+		return nil, fmt.Errorf("%w: %.0f rows of %.0f counters", ErrFilterTooLarge, depth, width)
+	}
+
+	kh, err := keyHasherFor[T](optionsConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+	return &CountMinSketch[T]{
+		keyHasher:    kh,
+		counters:     make([]uint64, uint64(width)*uint64(depth)),
+		width:        uint64(width),
+		depth:        uint64(depth),
+		conservative: cfg.ConservativeUpdate,
+	}, nil
+}
+
+// cells returns the index of the key's counter in each row.
+func (cm *CountMinSketch[T]) cells(data T, dst []uint64) []uint64 {
+	h1, h2 := cm.sum(data)
+	probes := ihash.NewProbes(h1, h2, cm.width)
+	for row := range cm.depth {
+		dst = append(dst, row*cm.width+probes.Next())
+	}
+	return dst
+}
+
+// addSaturating returns a+b, or math.MaxUint64 if the sum overflows.
+func addSaturating(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+// Add records n more occurrences of data. Counters saturate at
+// math.MaxUint64 rather than wrap.
+func (cm *CountMinSketch[T]) Add(data T, n uint64) {
+	if n == 0 {
+		return
+	}
+	var buf [16]uint64
+	cells := cm.cells(data, buf[:0])
+	cm.total = addSaturating(cm.total, n)
+
+	if !cm.conservative {
+		for _, c := range cells {
+			cm.counters[c] = addSaturating(cm.counters[c], n)
+		}
+		return
+	}
+	target := addSaturating(cm.min(cells), n)
+	for _, c := range cells {
+		cm.counters[c] = max(cm.counters[c], target)
+	}
+}
+
+// Estimate returns the estimated number of times data was added. It is never
+// less than the true count.
+func (cm *CountMinSketch[T]) Estimate(data T) uint64 {
+	var buf [16]uint64
+	return cm.min(cm.cells(data, buf[:0]))
+}
+
+func (cm *CountMinSketch[T]) min(cells []uint64) uint64 {
+	est := uint64(math.MaxUint64)
+	for _, c := range cells {
+		est = min(est, cm.counters[c])
+	}
+	return est
+}
+
+// Compatible reports whether other can be merged into cm: both must have
+// the same width, depth, hash family, key and key encoder.
+func (cm *CountMinSketch[T]) Compatible(other *CountMinSketch[T]) error {
+	switch {
+	case cm.width != other.width:
+		return &IncompatibleError{"width", cm.width, other.width}
+	case cm.depth != other.depth:
+		return &IncompatibleError{"depth", cm.depth, other.depth}
+	}
+	return cm.keyHasher.compatible(&other.keyHasher)
+}
+
+// MergeWith adds the counts of other to cm, as if every Add made on other
+// had been made on cm. Merging conservative sketches keeps the error
+// guarantee, but estimates may be higher than had the two streams been
+// added to one sketch.
+func (cm *CountMinSketch[T]) MergeWith(other *CountMinSketch[T]) error {
+	if err := cm.Compatible(other); err != nil {
+		return err
+	}
+	for i, c := range other.counters {
+		cm.counters[i] = addSaturating(cm.counters[i], c)
+	}
+	cm.total = addSaturating(cm.total, other.total)
+	return nil
+}
+
+// Merge returns a new sketch holding the counts of both cm and other.
+// See MergeWith.
+func (cm *CountMinSketch[T]) Merge(other *CountMinSketch[T]) (*CountMinSketch[T], error) {
+	result := *cm
+	result.keyHasher = newKeyHasher(cm.family, cm.key, cm.enc)
+	result.counters = slices.Clone(cm.counters)
+	if err := result.MergeWith(other); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Total returns the sum of all counts added.
+func (cm *CountMinSketch[T]) Total() uint64 {
+	return cm.total
+}
+
+// Width returns the number of counters per row.
+func (cm *CountMinSketch[T]) Width() uint64 {
+	return cm.width
+}
+
+// Depth returns the number of rows, one counter of which each key adds to.
+func (cm *CountMinSketch[T]) Depth() uint64 {
+	return cm.depth
+}
+
+// Conservative reports whether the sketch uses conservative updates.
+func (cm *CountMinSketch[T]) Conservative() bool {
+	return cm.conservative
+}
+
+// Epsilon returns the error bound as a fraction of Total, e/Width, which
+// may be tighter than the one requested.
+func (cm *CountMinSketch[T]) Epsilon() float64 {
+	return math.E / float64(cm.width)
+}
+
+// Delta returns the probability that an estimate exceeds the true count by
+// more than Epsilon times Total, e^-Depth.
+func (cm *CountMinSketch[T]) Delta() float64 {
+	return math.Exp(-float64(cm.depth))
+}
+
+// Size returns the total bit size of the counters.
+func (cm *CountMinSketch[T]) Size() uint64 {
+	return uint64(len(cm.counters)) * 64
+}
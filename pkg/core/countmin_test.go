@@ -0,0 +1,154 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestNewCountMin(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       CountMinConfig
+		wantErr   error
+		wantWidth uint64
+		wantDepth uint64
+	}{
+		{"default delta", CountMinConfig{Epsilon: 0.01}, nil, 272, 5},
+		{"custom delta", CountMinConfig{Epsilon: 0.001, Delta: 0.0001}, nil, 2719, 10},
+		{"no epsilon", CountMinConfig{}, ErrInvalidAccuracy, 0, 0},
+		{"epsilon out of range", CountMinConfig{Epsilon: 1}, ErrInvalidAccuracy, 0, 0},
+		{"delta out of range", CountMinConfig{Epsilon: 0.01, Delta: -0.5}, ErrInvalidAccuracy, 0, 0},
+		{"too large", CountMinConfig{Epsilon: 1e-300}, ErrFilterTooLarge, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm, err := NewCountMin[string](tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewCountMin() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cm.Width() != tt.wantWidth || cm.Depth() != tt.wantDepth {
+				t.Errorf("Width(), Depth() = %d, %d, want %d, %d", cm.Width(), cm.Depth(), tt.wantWidth, tt.wantDepth)
+			}
+			if cm.Epsilon() > tt.cfg.Epsilon {
+				t.Errorf("Epsilon() = %g, want at most %g", cm.Epsilon(), tt.cfg.Epsilon)
+			}
+		})
+	}
+}
+
+// zipfCounts adds a skewed stream of n occurrences over keys to cm and
+// returns the true count of each key.
+func zipfCounts(cm *CountMinSketch[int], keys, n int) map[int]uint64 {
+	r := rand.New(rand.NewPCG(3, 4))
+	z := rand.NewZipf(r, 1.1, 1, uint64(keys-1))
+	counts := make(map[int]uint64)
+	for range n {
+		key := int(z.Uint64())
+		cm.Add(key, 1)
+		counts[key]++
+	}
+	return counts
+}
+
+func TestCountMinSketch_Estimate(t *testing.T) {
+	for _, conservative := range []bool{false, true} {
+		cm := Must(NewCountMin[int](CountMinConfig{Epsilon: 0.001, ConservativeUpdate: conservative}))
+		counts := zipfCounts(cm, 100000, 200000)
+		if cm.Total() != 200000 {
+			t.Errorf("conservative=%v: Total() = %d, want 200000", conservative, cm.Total())
+		}
+
+		bound := uint64(cm.Epsilon() * float64(cm.Total()))
+		var over int
+		for key, n := range counts {
+			est := cm.Estimate(key)
+			if est < n {
+				t.Fatalf("conservative=%v: Estimate(%d) = %d, below the true count %d", conservative, key, est, n)
+			}
+			if est-n > bound {
+				over++
+			}
+		}
+		if rate := float64(over) / float64(len(counts)); rate > cm.Delta() {
+			t.Errorf("conservative=%v: %g of estimates exceed the bound, want at most %g", conservative, rate, cm.Delta())
+		}
+	}
+}
+
+func TestCountMinSketch_Conservative(t *testing.T) {
+	standard := Must(NewCountMin[int](CountMinConfig{Epsilon: 0.01}))
+	conservative := Must(NewCountMin[int](CountMinConfig{Epsilon: 0.01, ConservativeUpdate: true}, WithKey(standard.Key())))
+	counts := zipfCounts(standard, 10000, 50000)
+	zipfCounts(conservative, 10000, 50000)
+
+	var errStandard, errConservative uint64
+	for key, n := range counts {
+		if c, s := conservative.Estimate(key), standard.Estimate(key); c > s {
+			t.Fatalf("Estimate(%d) = %d conservative, %d standard, want conservative at most standard", key, c, s)
+		}
+		errStandard += standard.Estimate(key) - n
+		errConservative += conservative.Estimate(key) - n
+	}
+	if errConservative >= errStandard {
+		t.Errorf("total overestimate = %d conservative, %d standard, want conservative lower", errConservative, errStandard)
+	}
+}
+
+func TestCountMinSketch_Saturates(t *testing.T) {
+	cm := Must(NewCountMin[string](CountMinConfig{Epsilon: 0.1}))
+	cm.Add("a", math.MaxUint64-1)
+	cm.Add("a", 5)
+	cm.Add("b", 0)
+	if got := cm.Estimate("a"); got != math.MaxUint64 {
+		t.Errorf("Estimate(a) = %d, want %d", got, uint64(math.MaxUint64))
+	}
+	if cm.Total() != math.MaxUint64 {
+		t.Errorf("Total() = %d, want %d", cm.Total(), uint64(math.MaxUint64))
+	}
+}
+
+func TestCountMinSketch_Merge(t *testing.T) {
+	a := Must(NewCountMin[string](CountMinConfig{Epsilon: 0.01}))
+	b := Must(NewCountMin[string](CountMinConfig{Epsilon: 0.01}, WithKey(a.Key())))
+	both := Must(NewCountMin[string](CountMinConfig{Epsilon: 0.01}, WithKey(a.Key())))
+	for i, key := range []string{"x", "y", "z", "x", "w"} {
+		a.Add(key, uint64(i+1))
+		both.Add(key, uint64(i+1))
+	}
+	for i, key := range []string{"y", "v", "x"} {
+		b.Add(key, uint64(10*i+1))
+		both.Add(key, uint64(10*i+1))
+	}
+
+	m, err := a.Merge(b)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	for _, key := range []string{"v", "w", "x", "y", "z", "u"} {
+		if m.Estimate(key) != both.Estimate(key) {
+			t.Errorf("Estimate(%q) = %d after Merge, want %d", key, m.Estimate(key), both.Estimate(key))
+		}
+	}
+	if m.Total() != both.Total() {
+		t.Errorf("Total() = %d, want %d", m.Total(), both.Total())
+	}
+	if a.Estimate("v") != 0 || a.Total() != 15 {
+		t.Error("Merge() modified its receiver")
+	}
+
+	c := Must(NewCountMin[string](CountMinConfig{Epsilon: 0.02}, WithKey(a.Key())))
+	var ie *IncompatibleError
+	if err := a.MergeWith(c); !errors.As(err, &ie) || ie.Field != "width" {
+		t.Errorf("MergeWith() error = %v, want IncompatibleError on width", err)
+	}
+	d := Must(NewCountMin[string](CountMinConfig{Epsilon: 0.01}, WithSecretKey()))
+	if err := a.MergeWith(d); !errors.As(err, &ie) || ie.Field != "key" {
+		t.Errorf("MergeWith() error = %v, want IncompatibleError on key", err)
+	}
+}
@@ -0,0 +1,339 @@
+package core
+
+import (
+	"alex/bvs/internal/packed"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+)
+
+const (
+	defaultBucketSize      = 4
+	defaultFingerprintBits = 16
+	defaultMaxKicks        = 500
+)
+
+// CuckooFilter is an approximate set that supports deletion. It stores a
+// short fingerprint of each element in one of two candidate buckets; when
+// both are full, an insert evicts a random resident to its alternate bucket,
+// repeating up to MaxKicks times. The evictions are drawn from a generator
+// seeded from the filter's key through a one-way step, so they do not give
+// the key away. Duplicates are stored separately, so an
+// element inserted twice must be deleted twice, and at most 2*BucketSize
+// copies of one element fit.
+// The alternate bucket is derived from the current bucket and the
+// fingerprint alone (partial-key cuckoo hashing), so elements can be
+// relocated without their keys.
+// see Fan et al., "Cuckoo Filter: Practically Better Than Bloom"
+// A CuckooFilter is not safe for concurrent use.
+type CuckooFilter[T any] struct {
+	keyHasher[T]
+	slots      *packed.Array
+	mask       uint64
+	bucketSize uint64
+	maxKicks   int
+	victim     cuckooVictim
+	count      uint64
+	rng        *rand.Rand
+}
+
+// cuckooVictim holds the fingerprint left homeless by an insert that ran out
+// of kicks, so no element is ever lost.
+type cuckooVictim struct {
+	index, fp uint64
+	used      bool
+}
+
+// CuckooConfig describes a cuckoo filter. Size it by Capacity, the number of
+// elements to hold, or by Bits, the memory to use; the bucket count is
+// rounded to a power of two.
+type CuckooConfig struct {
+	// Capacity is the number of elements the filter must hold. It cannot be
+	// combined with Bits.
+	Capacity uint64
+	// Bits is the memory the buckets may take, in bits.
+	Bits uint64
+	// FalsePositiveRate is the target rate, in (0, 1). It derives the
+	// fingerprint width when FingerprintBits is zero.
+	FalsePositiveRate float64
+	// FingerprintBits is the width of each fingerprint, in [1, 32]. Zero
+	// derives it from FalsePositiveRate, or selects 16.
+	FingerprintBits uint8
+	// BucketSize is the number of fingerprints per bucket, in [1, 16]. Zero
+	// selects 4.
+	BucketSize uint8
+	// MaxKicks bounds the evictions an insert attempts before giving up. Zero
+	// selects 500.
+	MaxKicks int
+}
+
+// NewCuckoo creates a cuckoo filter described by cfg. Only the hashing
+// options apply: WithEncoder, WithHashFamily, WithKey, WithSecretKey and
+// WithFixedKey.
+func NewCuckoo[T any](cfg CuckooConfig, opts ...Option) (*CuckooFilter[T], error) {
+	if cfg.FalsePositiveRate != 0 && !(cfg.FalsePositiveRate > 0 && cfg.FalsePositiveRate < 1) {
+		return nil, fmt.Errorf("%w: %g", ErrInvalidFalsePositiveRate, cfg.FalsePositiveRate)
+	}
+
+	b := uint64(cfg.BucketSize)
+	if b == 0 {
+		b = defaultBucketSize
+	}
+	if b > 16 {
+		return nil, fmt.Errorf("%w: bucket size %d exceeds 16", ErrInvalidSize, b)
+	}
+	f := uint64(cfg.FingerprintBits)
+	switch {
+	case f != 0:
+	case cfg.FalsePositiveRate != 0:
+		// A lookup compares 2b fingerprints, each matching with chance 2^-f.
+		f = uint64(max(math.Ceil(math.Log2(2*float64(b)/cfg.FalsePositiveRate)), 1))
+	default:
+		f = defaultFingerprintBits
+	}
+	if f > 32 {
+		return nil, fmt.Errorf("%w: fingerprint of %d bits exceeds 32", ErrInvalidWidth, f)
+	}
+	kicks := cfg.MaxKicks
+	if kicks == 0 {
+		kicks = defaultMaxKicks
+	}
+	if kicks < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidMaxKicks, kicks)
+	}
+
+	var buckets uint64
+	switch {
+	case cfg.Bits != 0 && cfg.Capacity != 0:
+		return nil, fmt.Errorf("%w: Bits and Capacity are mutually exclusive", ErrConflictingConfig)
+	case cfg.Bits != 0:
+		if n := cfg.Bits / (b * f); n != 0 {
+			buckets = 1 << (bits.Len64(n) - 1)
+		}
+	case cfg.Capacity != 0:
+		n := math.Ceil(float64(cfg.Capacity) / (cuckooLoadFactor(b) * float64(b)))
+		if exceedsMaxBits(n * float64(b*f)) {
+			return nil, fmt.Errorf("%w: %d elements", ErrFilterTooLarge, cfg.Capacity)
+		}
+		buckets = 1 << bits.Len64(uint64(n)-1)
+	}
+	if buckets == 0 {
+		return nil, ErrInvalidSize
+	}
+	if buckets*b*f > maxBits {
+		return nil, fmt.Errorf("%w: %d buckets of %d bits", ErrFilterTooLarge, buckets, b*f)
+	}
+
+	kh, err := keyHasherFor[T](optionsConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+	seed0, seed1 := rngSeeds(kh.key)
+	return &CuckooFilter[T]{
+		keyHasher:  kh,
+		slots:      packed.NewArray(buckets*b, uint8(f)),
+		mask:       buckets - 1,
+		bucketSize: b,
+		maxKicks:   kicks,
+		rng:        rand.New(rand.NewPCG(seed0, seed1)),
+	}, nil
+}
+
+// cuckooLoadFactor is the load at which inserts start failing for a bucket
+// size, as measured in the paper; capacity is sized against it.
+func cuckooLoadFactor(b uint64) float64 {
+	switch b {
+	case 1:
+		return 0.5
+	case 2, 3:
+		return 0.84
+	default:
+		return 0.95
+	}
+}
+
+// locate returns the key's first bucket and its non-zero fingerprint;
+// a zero slot is empty.
+func (cf *CuckooFilter[T]) locate(data T) (uint64, uint64) {
+	h1, h2 := cf.sum(data)
+	fp := h2 >> (64 - cf.slots.Width())
+	if fp == 0 {
+		fp = 1
+	}
+	return h1 & cf.mask, fp
+}
+
+// alt returns the other candidate bucket of a fingerprint in bucket i.
+// It is an involution: alt(alt(i, fp), fp) == i.
+func (cf *CuckooFilter[T]) alt(i, fp uint64) uint64 {
+	return (i ^ fp*0x5bd1e995) & cf.mask
+}
+
+// add stores fp in a free slot of bucket i, reporting whether there was one.
+func (cf *CuckooFilter[T]) add(i, fp uint64) bool {
+	start := i * cf.bucketSize
+	for s := start; s < start+cf.bucketSize; s++ {
+		if cf.slots.Get(s) == 0 {
+			cf.slots.Set(s, fp)
+			return true
+		}
+	}
+	return false
+}
+
+// remove clears one slot of bucket i holding fp, reporting whether it found one.
+func (cf *CuckooFilter[T]) remove(i, fp uint64) bool {
+	start := i * cf.bucketSize
+	for s := start; s < start+cf.bucketSize; s++ {
+		if cf.slots.Get(s) == fp {
+			cf.slots.Set(s, 0)
+			return true
+		}
+	}
+	return false
+}
+
+// matches counts the slots of bucket i holding fp.
+func (cf *CuckooFilter[T]) matches(i, fp uint64) uint64 {
+	var n uint64
+	start := i * cf.bucketSize
+	for s := start; s < start+cf.bucketSize; s++ {
+		if cf.slots.Get(s) == fp {
+			n++
+		}
+	}
+	return n
+}
+
+// place stores fp in bucket i or its alternate, evicting residents as
+// needed. If it runs out of kicks, the last evicted fingerprint becomes the
+// victim and place reports false.
+func (cf *CuckooFilter[T]) place(i, fp uint64) bool {
+	if cf.add(i, fp) {
+		return true
+	}
+	i = cf.alt(i, fp)
+	if cf.add(i, fp) {
+		return true
+	}
+
+	for range cf.maxKicks {
+		s := i*cf.bucketSize + cf.rng.Uint64N(cf.bucketSize)
+		evicted := cf.slots.Get(s)
+		cf.slots.Set(s, fp)
+		fp = evicted
+		i = cf.alt(i, fp)
+		if cf.add(i, fp) {
+			return true
+		}
+	}
+	cf.victim = cuckooVictim{index: i, fp: fp, used: true}
+	return false
+}
+
+// Insert adds an element to the filter. It returns ErrFilterFull from the
+// insert that runs out of kicks. That element is still added: the
+// fingerprint its kicks left without a slot, its own or another's, is kept
+// in the filter's one-entry stash, so no element is lost. While the stash is
+// occupied, further inserts return ErrFilterFull and leave the filter
+// unchanged; deleting an element moves the stashed fingerprint back into
+// the table if there is room.
+func (cf *CuckooFilter[T]) Insert(data T) error {
+	if cf.victim.used {
+		return ErrFilterFull
+	}
+	i, fp := cf.locate(data)
+	cf.count++
+	if !cf.place(i, fp) {
+		return ErrFilterFull
+	}
+	return nil
+}
+
+// Contains checks if an element might be in the filter.
+// Returns true if the element might be present (with possible false positives).
+// Returns false if the element is definitely not present.
+func (cf *CuckooFilter[T]) Contains(data T) bool {
+	return cf.Count(data) != 0
+}
+
+// Count returns how many times an element appears to have been inserted and
+// not deleted. Fingerprint collisions can only make it larger.
+func (cf *CuckooFilter[T]) Count(data T) uint64 {
+	i1, fp := cf.locate(data)
+	i2 := cf.alt(i1, fp)
+
+	n := cf.matches(i1, fp)
+	if i2 != i1 {
+		n += cf.matches(i2, fp)
+	}
+	if v := cf.victim; v.used && v.fp == fp && (v.index == i1 || v.index == i2) {
+		n++
+	}
+	return n
+}
+
+// Delete removes one occurrence of an element. It returns ErrNotPresent if
+// the element is definitely not in the filter. Deleting an element that was
+// never inserted but is reported present by a false positive removes
+// another element's fingerprint.
+func (cf *CuckooFilter[T]) Delete(data T) error {
+	i1, fp := cf.locate(data)
+	i2 := cf.alt(i1, fp)
+
+	if v := cf.victim; v.used && v.fp == fp && (v.index == i1 || v.index == i2) {
+		cf.victim = cuckooVictim{}
+		cf.count--
+		return nil
+	}
+	if !cf.remove(i1, fp) && !cf.remove(i2, fp) {
+		return ErrNotPresent
+	}
+	cf.count--
+
+	if v := cf.victim; v.used {
+		cf.victim = cuckooVictim{}
+		cf.place(v.index, v.fp)
+	}
+	return nil
+}
+
+// Len returns the number of elements inserted and not yet deleted.
+func (cf *CuckooFilter[T]) Len() uint64 {
+	return cf.count
+}
+
+// Capacity returns the number of fingerprint slots; inserts typically start
+// failing at a load of 50% to 95% of it, depending on the bucket size.
+func (cf *CuckooFilter[T]) Capacity() uint64 {
+	return cf.slots.Len()
+}
+
+// LoadFactor returns the fraction of slots in use.
+func (cf *CuckooFilter[T]) LoadFactor() float64 {
+	return float64(cf.count) / float64(cf.slots.Len())
+}
+
+// Size returns the total bit size of the fingerprint slots.
+func (cf *CuckooFilter[T]) Size() uint64 {
+	return cf.slots.Len() * uint64(cf.slots.Width())
+}
+
+// FingerprintBits returns the width of each fingerprint in bits.
+func (cf *CuckooFilter[T]) FingerprintBits() uint8 {
+	return cf.slots.Width()
+}
+
+// BucketSize returns the number of fingerprints per bucket.
+func (cf *CuckooFilter[T]) BucketSize() uint8 {
+	return uint8(cf.bucketSize)
+}
+
+// FalsePositiveRate returns the false-positive rate of a full filter: the
+// chance that one of the 2*BucketSize fingerprints a lookup compares matches
+// by accident.
+func (cf *CuckooFilter[T]) FalsePositiveRate() float64 {
+	miss := 1 - math.Ldexp(1, -int(cf.slots.Width()))
+	return 1 - math.Pow(miss, float64(2*cf.bucketSize))
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"alex/bvs/pkg/hash"
+	"errors"
+	"testing"
+)
+
+func TestNewCuckoo(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        CuckooConfig
+		wantErr    error
+		wantFP     uint8
+		wantBucket uint8
+		wantCap    uint64
+	}{
+		{"by capacity", CuckooConfig{Capacity: 1000}, nil, 16, 4, 2048},
+		{"by bits", CuckooConfig{Bits: 1 << 16}, nil, 16, 4, 4096},
+		{"rate sets fingerprint", CuckooConfig{Capacity: 1000, FalsePositiveRate: 0.001}, nil, 13, 4, 2048},
+		{"custom sizes", CuckooConfig{Capacity: 1000, FingerprintBits: 8, BucketSize: 2}, nil, 8, 2, 2048},
+		{"no size", CuckooConfig{}, ErrInvalidSize, 0, 0, 0},
+		{"too few bits", CuckooConfig{Bits: 10}, ErrInvalidSize, 0, 0, 0},
+		{"bits and capacity", CuckooConfig{Bits: 1024, Capacity: 10}, ErrConflictingConfig, 0, 0, 0},
+		{"fingerprint too wide", CuckooConfig{Capacity: 10, FingerprintBits: 33}, ErrInvalidWidth, 0, 0, 0},
+		{"bucket too large", CuckooConfig{Capacity: 10, BucketSize: 17}, ErrInvalidSize, 0, 0, 0},
+		{"negative kicks", CuckooConfig{Capacity: 10, MaxKicks: -1}, ErrInvalidMaxKicks, 0, 0, 0},
+		{"invalid rate", CuckooConfig{Capacity: 10, FalsePositiveRate: 1}, ErrInvalidFalsePositiveRate, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewCuckoo[string](tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewCuckoo() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if f.FingerprintBits() != tt.wantFP || f.BucketSize() != tt.wantBucket || f.Capacity() != tt.wantCap {
+				t.Errorf("FingerprintBits(), BucketSize(), Capacity() = %d, %d, %d, want %d, %d, %d",
+					f.FingerprintBits(), f.BucketSize(), f.Capacity(), tt.wantFP, tt.wantBucket, tt.wantCap)
+			}
+		})
+	}
+}
+
+func TestCuckooFilter_InsertDelete(t *testing.T) {
+	const n = 10000
+	f := Must(NewCuckoo[int](CuckooConfig{Capacity: n}))
+	for i := 0; i < n; i++ {
+		if err := f.Insert(i); err != nil {
+			t.Fatalf("Insert(%d) error = %v", i, err)
+		}
+	}
+	if f.Len() != n {
+		t.Errorf("Len() = %d, want %d", f.Len(), n)
+	}
+	for i := 0; i < n; i++ {
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false, want true", i)
+		}
+	}
+
+	for i := 0; i < n; i += 2 {
+		if err := f.Delete(i); err != nil {
+			t.Fatalf("Delete(%d) error = %v", i, err)
+		}
+	}
+	for i := 1; i < n; i += 2 {
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false for a kept element, want true", i)
+		}
+	}
+	present := 0
+	for i := 0; i < n; i += 2 {
+		if f.Contains(i) {
+			present++
+		}
+	}
+	if present > 5 {
+		t.Errorf("%d of %d deleted elements still present, want almost none", present, n/2)
+	}
+	if err := f.Delete(-1); !errors.Is(err, ErrNotPresent) {
+		t.Errorf("Delete(absent) error = %v, want %v", err, ErrNotPresent)
+	}
+}
+
+func TestCuckooFilter_Count(t *testing.T) {
+	f := Must(NewCuckoo[string](CuckooConfig{Capacity: 100}))
+	for i := 0; i < 3; i++ {
+		f.Insert("dup")
+	}
+	f.Insert("once")
+
+	if got := f.Count("dup"); got != 3 {
+		t.Errorf("Count(dup) = %d, want 3", got)
+	}
+	if got := f.Count("once"); got != 1 {
+		t.Errorf("Count(once) = %d, want 1", got)
+	}
+	f.Delete("dup")
+	if got := f.Count("dup"); got != 2 {
+		t.Errorf("Count(dup) after Delete = %d, want 2", got)
+	}
+}
+
+func TestCuckooFilter_Full(t *testing.T) {
+	f := Must(NewCuckoo[int](CuckooConfig{Bits: 64 * 16, MaxKicks: 50}))
+
+	var inserted []int
+	var err error
+	for i := 0; err == nil; i++ {
+		err = f.Insert(i)
+		inserted = append(inserted, i)
+	}
+	// The insert that filled the filter reports it, and is kept in the stash.
+	last := inserted[len(inserted)-1]
+	if !errors.Is(err, ErrFilterFull) {
+		t.Fatalf("Insert(%d) error = %v, want %v", last, err, ErrFilterFull)
+	}
+	if f.Len() != uint64(len(inserted)) || !f.Contains(last) {
+		t.Errorf("Len(), Contains(%d) = %d, %v after the failing insert, want %d, true", last, f.Len(), f.Contains(last), len(inserted))
+	}
+	if err := f.Insert(-2); !errors.Is(err, ErrFilterFull) || f.Len() != uint64(len(inserted)) {
+		t.Errorf("Insert() into a full filter error = %v, Len() = %d, want %v, %d", err, f.Len(), ErrFilterFull, len(inserted))
+	}
+	if f.LoadFactor() < 0.8 {
+		t.Errorf("LoadFactor() = %g when full, want at least 0.8", f.LoadFactor())
+	}
+	for _, i := range inserted {
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false after filling up, want true", i)
+		}
+	}
+
+	// Freeing half the slots leaves the stashed fingerprint room to return.
+	half := len(inserted) / 2
+	for _, i := range inserted[:half] {
+		if err := f.Delete(i); err != nil {
+			t.Fatalf("Delete(%d) error = %v", i, err)
+		}
+	}
+	if err := f.Insert(-1); err != nil {
+		t.Errorf("Insert() after Delete error = %v, want nil", err)
+	}
+	for _, i := range inserted[half:] {
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false after Delete, want true", i)
+		}
+	}
+}
+
+func TestCuckooFilter_FalsePositiveRate(t *testing.T) {
+	const n = 20000
+	f := Must(NewCuckoo[int](CuckooConfig{Capacity: n, FalsePositiveRate: 0.01}))
+	for i := 0; i < n; i++ {
+		f.Insert(i)
+	}
+
+	var fp int
+	for i := n; i < 11*n; i++ {
+		if f.Contains(i) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / (10 * n); rate > 0.01 {
+		t.Errorf("false-positive rate = %g, want at most 0.01 (bound %g)", rate, f.FalsePositiveRate())
+	}
+}
+
+func TestCuckooFilter_HashFamily(t *testing.T) {
+	f := Must(NewCuckoo[string](CuckooConfig{Capacity: 100}, WithHashFamily(hash.XXHash64), WithEncoder(FmtEncoder[string]())))
+	if f.HashFamily() != hash.XXHash64 || f.Encoder().ID() != EncoderFmt {
+		t.Errorf("HashFamily(), Encoder().ID() = %q, %d, want %q, %d", f.HashFamily(), f.Encoder().ID(), hash.XXHash64, EncoderFmt)
+	}
+	f.Insert("a")
+	if !f.Contains("a") {
+		t.Error("Contains(a) = false, want true")
+	}
+}
@@ -0,0 +1,254 @@
+package core
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+// EncoderID identifies a key encoding, so filters built with different
+// encoders can be told apart.
+type EncoderID uint8
+
+const (
+	// EncoderCustom marks user-provided encoders.
+	EncoderCustom EncoderID = iota
+	EncoderInt
+	EncoderUint
+	EncoderFloat
+	EncoderString
+	EncoderBytes
+	EncoderBinary
+	EncoderFmt
+)
+
+// Encoder converts keys of type T to the bytes the filter hashes.
+// Equal keys must encode to equal bytes.
+type Encoder[T any] interface {
+	// ID identifies the encoding.
+	ID() EncoderID
+	// AppendKey appends the encoding of key to dst and returns the extended slice.
+	AppendKey(dst []byte, key T) []byte
+}
+
+// IntEncoder encodes signed integers as 8 little-endian bytes.
+func IntEncoder[T ~int | ~int8 | ~int16 | ~int32 | ~int64]() Encoder[T] {
+	return intEncoder[T]{}
+}
+
+type intEncoder[T ~int | ~int8 | ~int16 | ~int32 | ~int64] struct{}
+
+func (intEncoder[T]) ID() EncoderID { return EncoderInt }
+
+func (intEncoder[T]) AppendKey(dst []byte, key T) []byte {
+	return binary.LittleEndian.AppendUint64(dst, uint64(int64(key)))
+}
+
+// UintEncoder encodes unsigned integers as 8 little-endian bytes.
+func UintEncoder[T ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr]() Encoder[T] {
+	return uintEncoder[T]{}
+}
+
+type uintEncoder[T ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr] struct{}
+
+func (uintEncoder[T]) ID() EncoderID { return EncoderUint }
+
+func (uintEncoder[T]) AppendKey(dst []byte, key T) []byte {
+	return binary.LittleEndian.AppendUint64(dst, uint64(key))
+}
+
+// FloatEncoder encodes floats by their float64 bit pattern.
+// Negative zero is encoded as zero, since the two compare equal.
+func FloatEncoder[T ~float32 | ~float64]() Encoder[T] {
+	return floatEncoder[T]{}
+}
+
+type floatEncoder[T ~float32 | ~float64] struct{}
+
+func (floatEncoder[T]) ID() EncoderID { return EncoderFloat }
+
+func (floatEncoder[T]) AppendKey(dst []byte, key T) []byte {
+	f := float64(key)
+	if f == 0 {
+		f = 0
+	}
+	return binary.LittleEndian.AppendUint64(dst, math.Float64bits(f))
+}
+
+// StringEncoder encodes strings as their raw bytes.
+func StringEncoder[T ~string]() Encoder[T] {
+	return stringEncoder[T]{}
+}
+
+type stringEncoder[T ~string] struct{}
+
+func (stringEncoder[T]) ID() EncoderID { return EncoderString }
+
+func (stringEncoder[T]) AppendKey(dst []byte, key T) []byte {
+	return append(dst, key...)
+}
+
+// BytesEncoder encodes byte slices as themselves.
+func BytesEncoder[T ~[]byte]() Encoder[T] {
+	return bytesEncoder[T]{}
+}
+
+type bytesEncoder[T ~[]byte] struct{}
+
+func (bytesEncoder[T]) ID() EncoderID { return EncoderBytes }
+
+func (bytesEncoder[T]) AppendKey(dst []byte, key T) []byte {
+	return append(dst, key...)
+}
+
+// BinaryEncoder encodes keys with their MarshalBinary method.
+// Keys must marshal without error; a failing MarshalBinary panics.
+func BinaryEncoder[T encoding.BinaryMarshaler]() Encoder[T] {
+	return binaryEncoder[T]{}
+}
+
+type binaryEncoder[T any] struct{}
+
+func (binaryEncoder[T]) ID() EncoderID { return EncoderBinary }
+
+func (binaryEncoder[T]) AppendKey(dst []byte, key T) []byte {
+	data, err := any(key).(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		panic(fmt.Sprintf("encoding key: %v", err))
+	}
+	return append(dst, data...)
+}
+
+// FmtEncoder encodes keys by formatting them with fmt. It works for any type
+// but is slow, allocates, and renders pointers by address; it is the fallback
+// for types without a dedicated encoder.
+func FmtEncoder[T any]() Encoder[T] {
+	return fmtEncoder[T]{}
+}
+
+type fmtEncoder[T any] struct{}
+
+func (fmtEncoder[T]) ID() EncoderID { return EncoderFmt }
+
+func (fmtEncoder[T]) AppendKey(dst []byte, key T) []byte {
+	return fmt.Appendf(dst, "%T.%v", key, key)
+}
+
+// kindEncoder encodes a named type by the kind of its underlying type,
+// producing the same bytes and ID as the typed encoder for that kind, e.g.
+// IntEncoder for a type UserID int64.
+type kindEncoder[T any] struct {
+	kind reflect.Kind
+}
+
+func (e kindEncoder[T]) ID() EncoderID {
+	switch e.kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return EncoderInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return EncoderUint
+	case reflect.Float32, reflect.Float64:
+		return EncoderFloat
+	case reflect.String:
+		return EncoderString
+	default:
+		return EncoderBytes
+	}
+}
+
+func (e kindEncoder[T]) AppendKey(dst []byte, key T) []byte {
+	// The kind was checked against T's underlying type at construction, so
+	// key can be read as that type.
+	p := unsafe.Pointer(&key)
+	switch e.kind {
+	case reflect.Int:
+		return intEncoder[int]{}.AppendKey(dst, *(*int)(p))
+	case reflect.Int8:
+		return intEncoder[int8]{}.AppendKey(dst, *(*int8)(p))
+	case reflect.Int16:
+		return intEncoder[int16]{}.AppendKey(dst, *(*int16)(p))
+	case reflect.Int32:
+		return intEncoder[int32]{}.AppendKey(dst, *(*int32)(p))
+	case reflect.Int64:
+		return intEncoder[int64]{}.AppendKey(dst, *(*int64)(p))
+	case reflect.Uint:
+		return uintEncoder[uint]{}.AppendKey(dst, *(*uint)(p))
+	case reflect.Uint8:
+		return uintEncoder[uint8]{}.AppendKey(dst, *(*uint8)(p))
+	case reflect.Uint16:
+		return uintEncoder[uint16]{}.AppendKey(dst, *(*uint16)(p))
+	case reflect.Uint32:
+		return uintEncoder[uint32]{}.AppendKey(dst, *(*uint32)(p))
+	case reflect.Uint64:
+		return uintEncoder[uint64]{}.AppendKey(dst, *(*uint64)(p))
+	case reflect.Uintptr:
+		return uintEncoder[uintptr]{}.AppendKey(dst, *(*uintptr)(p))
+	case reflect.Float32:
+		return floatEncoder[float32]{}.AppendKey(dst, *(*float32)(p))
+	case reflect.Float64:
+		return floatEncoder[float64]{}.AppendKey(dst, *(*float64)(p))
+	case reflect.String:
+		return append(dst, *(*string)(p)...)
+	default:
+		return append(dst, *(*[]byte)(p)...)
+	}
+}
+
+// defaultEncoder picks the built-in encoder for T. Named types use
+// MarshalBinary if they have it, or else the encoder for the kind of their
+// underlying type; fmt is the fallback for the types neither covers.
+func defaultEncoder[T any]() Encoder[T] {
+	var enc any
+	switch any((*T)(nil)).(type) {
+	case *int:
+		enc = IntEncoder[int]()
+	case *int8:
+		enc = IntEncoder[int8]()
+	case *int16:
+		enc = IntEncoder[int16]()
+	case *int32:
+		enc = IntEncoder[int32]()
+	case *int64:
+		enc = IntEncoder[int64]()
+	case *uint:
+		enc = UintEncoder[uint]()
+	case *uint8:
+		enc = UintEncoder[uint8]()
+	case *uint16:
+		enc = UintEncoder[uint16]()
+	case *uint32:
+		enc = UintEncoder[uint32]()
+	case *uint64:
+		enc = UintEncoder[uint64]()
+	case *uintptr:
+		enc = UintEncoder[uintptr]()
+	case *float32:
+		enc = FloatEncoder[float32]()
+	case *float64:
+		enc = FloatEncoder[float64]()
+	case *string:
+		enc = StringEncoder[string]()
+	case *[]byte:
+		enc = BytesEncoder[[]byte]()
+	default:
+		var zero T
+		if _, ok := any(zero).(encoding.BinaryMarshaler); ok {
+			return binaryEncoder[T]{}
+		}
+		switch t := reflect.TypeFor[T](); t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64, reflect.String:
+			return kindEncoder[T]{t.Kind()}
+		case reflect.Slice:
+			if t.Elem().Kind() == reflect.Uint8 {
+				return kindEncoder[T]{reflect.Slice}
+			}
+		}
+		return fmtEncoder[T]{}
+	}
+	return enc.(Encoder[T])
+}
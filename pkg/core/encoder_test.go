@@ -0,0 +1,171 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// Named types whose underlying types have typed encoders.
+type (
+	userID  int64
+	port    uint16
+	celsius float32
+	label   string
+	token   []byte
+)
+
+func TestEncoder_Default(t *testing.T) {
+	type Person struct {
+		Name string
+		Age  int
+	}
+
+	tests := []struct {
+		name   string
+		id     func() EncoderID
+		wantID EncoderID
+	}{
+		{"int", func() EncoderID { return defaultEncoder[int]().ID() }, EncoderInt},
+		{"int8", func() EncoderID { return defaultEncoder[int8]().ID() }, EncoderInt},
+		{"int64", func() EncoderID { return defaultEncoder[int64]().ID() }, EncoderInt},
+		{"uint16", func() EncoderID { return defaultEncoder[uint16]().ID() }, EncoderUint},
+		{"uintptr", func() EncoderID { return defaultEncoder[uintptr]().ID() }, EncoderUint},
+		{"float32", func() EncoderID { return defaultEncoder[float32]().ID() }, EncoderFloat},
+		{"float64", func() EncoderID { return defaultEncoder[float64]().ID() }, EncoderFloat},
+		{"string", func() EncoderID { return defaultEncoder[string]().ID() }, EncoderString},
+		{"bytes", func() EncoderID { return defaultEncoder[[]byte]().ID() }, EncoderBytes},
+		{"named int", func() EncoderID { return defaultEncoder[userID]().ID() }, EncoderInt},
+		{"named uint", func() EncoderID { return defaultEncoder[port]().ID() }, EncoderUint},
+		{"named float", func() EncoderID { return defaultEncoder[celsius]().ID() }, EncoderFloat},
+		{"named string", func() EncoderID { return defaultEncoder[label]().ID() }, EncoderString},
+		{"named bytes", func() EncoderID { return defaultEncoder[token]().ID() }, EncoderBytes},
+		{"binary marshaler", func() EncoderID { return defaultEncoder[time.Time]().ID() }, EncoderBinary},
+		{"named duration", func() EncoderID { return defaultEncoder[time.Duration]().ID() }, EncoderInt},
+		{"slice of ints falls back to fmt", func() EncoderID { return defaultEncoder[[]int]().ID() }, EncoderFmt},
+		{"struct falls back to fmt", func() EncoderID { return defaultEncoder[Person]().ID() }, EncoderFmt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.id(); got != tt.wantID {
+				t.Errorf("ID() = %d, want %d", got, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestEncoder_AppendKey(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []byte
+		want []byte
+	}{
+		{"int", IntEncoder[int]().AppendKey(nil, -1), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"int8 sign extends", IntEncoder[int8]().AppendKey(nil, -1), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"uint", UintEncoder[uint32]().AppendKey(nil, 0x01020304), []byte{4, 3, 2, 1, 0, 0, 0, 0}},
+		{"negative zero", FloatEncoder[float64]().AppendKey(nil, math.Copysign(0, -1)), FloatEncoder[float64]().AppendKey(nil, 0)},
+		{"float32 widens", FloatEncoder[float32]().AppendKey(nil, 1.5), FloatEncoder[float64]().AppendKey(nil, 1.5)},
+		{"string", StringEncoder[string]().AppendKey(nil, "abc"), []byte("abc")},
+		{"bytes", BytesEncoder[[]byte]().AppendKey(nil, []byte{1, 2}), []byte{1, 2}},
+		{"appends to dst", StringEncoder[string]().AppendKey([]byte("x"), "y"), []byte("xy")},
+		{"fmt", FmtEncoder[int]().AppendKey(nil, 7), []byte("int.7")},
+		{"named int", defaultEncoder[userID]().AppendKey(nil, -1), IntEncoder[int64]().AppendKey(nil, -1)},
+		{"named uint", defaultEncoder[port]().AppendKey(nil, 443), UintEncoder[uint16]().AppendKey(nil, 443)},
+		{"named float", defaultEncoder[celsius]().AppendKey(nil, -1.5), FloatEncoder[float32]().AppendKey(nil, -1.5)},
+		{"named float negative zero", defaultEncoder[celsius]().AppendKey(nil, celsius(math.Copysign(0, -1))), FloatEncoder[float32]().AppendKey(nil, 0)},
+		{"named string", defaultEncoder[label]().AppendKey([]byte("x"), "abc"), []byte("xabc")},
+		{"named bytes", defaultEncoder[token]().AppendKey(nil, token{1, 2}), []byte{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !bytes.Equal(tt.got, tt.want) {
+				t.Errorf("AppendKey() = %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncoder_WithEncoder(t *testing.T) {
+	type UserID int64
+
+	t.Run("named integer", func(t *testing.T) {
+		f := NewBloomFilter[UserID](1024, WithEncoder(IntEncoder[UserID]()))
+		if f.Encoder().ID() != EncoderInt {
+			t.Errorf("Encoder().ID() = %d, want %d", f.Encoder().ID(), EncoderInt)
+		}
+		f.Insert(42)
+		if !f.Contains(42) {
+			t.Error("Contains(42) = false, want true")
+		}
+	})
+
+	t.Run("byte slices", func(t *testing.T) {
+		f := NewBloomFilter[[]byte](1024)
+		f.Insert([]byte("key"))
+		if !f.Contains([]byte("key")) {
+			t.Error("Contains(key) = false, want true")
+		}
+		if f.Contains([]byte("other")) {
+			t.Error("Contains(other) = true, want false")
+		}
+	})
+
+	t.Run("mismatched key type", func(t *testing.T) {
+		_, err := NewWithEstimates[string](100, 0.01, WithEncoder(IntEncoder[int]()))
+		if !errors.Is(err, ErrEncoderMismatch) {
+			t.Errorf("NewWithEstimates() error = %v, want %v", err, ErrEncoderMismatch)
+		}
+	})
+
+	t.Run("mismatched key type panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("NewBloomFilter() did not panic, want panic")
+			}
+		}()
+		NewBloomFilter[string](1024, WithEncoder(IntEncoder[int]()))
+	})
+}
+
+func TestEncoder_ZeroAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops buffers at random under the race detector")
+	}
+	ints := NewBloomFilter[int](1024)
+	strings := NewBloomFilter[string](1024)
+	floats := NewBloomFilter[float64](1024)
+	ids := NewBloomFilter[userID](1024)
+	labels := NewBloomFilter[label](1024)
+	tokens := NewBloomFilter[token](1024)
+	ints.Insert(1)
+	strings.Insert("warm up the buffer")
+	floats.Insert(1)
+	ids.Insert(1)
+	labels.Insert("warm up the buffer")
+	tokens.Insert(token("warm up the buffer"))
+	tok := token("two")
+
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{"int", func() { ints.Insert(2); ints.Contains(3) }},
+		{"string", func() { strings.Insert("two"); strings.Contains("three") }},
+		{"float", func() { floats.Insert(2.5); floats.Contains(3.5) }},
+		{"named int", func() { ids.Insert(2); ids.Contains(3) }},
+		{"named string", func() { labels.Insert("two"); labels.Contains("three") }},
+		{"named bytes", func() { tokens.Insert(tok); tokens.Contains(tok) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(100, tt.fn); allocs != 0 {
+				t.Errorf("allocs per op = %v, want 0", allocs)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package core
+
+import "errors"
+
+var (
+	// ErrInvalidSize is returned when neither a bit size nor a capacity and
+	// false-positive rate are given.
+	ErrInvalidSize = errors.New("size must be greater than 0")
+	// ErrInvalidHashCount is returned when the hash count is out of range.
+	ErrInvalidHashCount = errors.New("invalid hash count")
+	// ErrInvalidCapacity is returned when the expected number of elements is zero.
+	ErrInvalidCapacity = errors.New("capacity must be greater than 0")
+	// ErrInvalidFalsePositiveRate is returned when the target false-positive rate is not in (0, 1).
+	ErrInvalidFalsePositiveRate = errors.New("false-positive rate must be in range (0, 1)")
+	// ErrFilterTooLarge is returned when the derived bit size does not fit the filter.
+	ErrFilterTooLarge = errors.New("filter size exceeds maximum")
+	// ErrEncoderMismatch is returned when an encoder does not accept the filter's key type.
+	ErrEncoderMismatch = errors.New("encoder does not match key type")
+	// ErrUnknownHashFamily is returned when a hash family is not registered.
+	ErrUnknownHashFamily = errors.New("unknown hash family")
+	// ErrConflictingConfig is returned when mutually exclusive settings are combined.
+	ErrConflictingConfig = errors.New("conflicting configuration")
+	// ErrInvalidFormat is returned when serialized data is malformed or truncated.
+	ErrInvalidFormat = errors.New("invalid serialized filter")
+	// ErrUnsupportedVersion is returned for serialized data of an unknown format version.
+	ErrUnsupportedVersion = errors.New("unsupported format version")
+	// ErrChecksumMismatch is returned when serialized data fails its CRC check.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrIncompatible is returned when filters with different parameters are combined.
+	ErrIncompatible = errors.New("incompatible filters")
+	// ErrNotPresent is returned when removing an element that is definitely not in the filter.
+	ErrNotPresent = errors.New("element not present")
+	// ErrInvalidScaling is returned when a scalable filter's growth factor or
+	// tightening ratio is out of range.
+	ErrInvalidScaling = errors.New("invalid scaling parameters")
+	// ErrInvalidWindow is returned when a windowed filter's rotation interval
+	// or generation count is out of range.
+	ErrInvalidWindow = errors.New("invalid window parameters")
+	// ErrInvalidAccuracy is returned when a sketch's epsilon or delta is not
+	// in (0, 1).
+	ErrInvalidAccuracy = errors.New("epsilon and delta must be in range (0, 1)")
+	// ErrInvalidWidth is returned when a counter, cell, fingerprint or
+	// remainder width is out of range.
+	ErrInvalidWidth = errors.New("invalid width")
+	// ErrInvalidMaxKicks is returned when a cuckoo filter's eviction bound is
+	// negative.
+	ErrInvalidMaxKicks = errors.New("max kicks must not be negative")
+	// ErrFilterFull is returned when a cuckoo filter has no room for another element.
+	ErrFilterFull = errors.New("filter is full")
+	// ErrDuplicateKey is returned when a static filter is built from a key set
+	// that contains the same key twice.
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrDecodeFailed is returned when an IBLT holds too many keys to list
+	// them all.
+	ErrDecodeFailed = errors.New("table could not be fully decoded")
+	// ErrValueTooWide is returned when a value does not fit a Bloomier's
+	// value width.
+	ErrValueTooWide = errors.New("value exceeds value width")
+	// ErrConstructionFailed is returned when a static filter cannot be built
+	// from its keys.
+	ErrConstructionFailed = errors.New("filter construction failed")
+)
@@ -2,64 +2,102 @@ package core
 
 import (
 	"alex/bvs/internal/bitset"
-	"alex/bvs/internal/hash"
+	ihash "alex/bvs/internal/hash"
 	"fmt"
 )
 
 // BloomFilter is a type-safe probabilistic data structure for testing set membership.
-// The generic type T is constrained to comparable to ensure proper equality checks.
-type BloomFilter[T comparable] struct {
+// Keys of any type T are converted to bytes by the filter's Encoder before hashing.
+// The number of hash functions is chosen at construction and never changes, so
+// every inserted element is checked against the same positions it was set with.
+// A BloomFilter is not safe for concurrent use, except that Contains may be
+// called concurrently with other calls that do not modify the filter.
+type BloomFilter[T any] struct {
+	keyHasher[T]
 	bs       *bitset.Bitset
-	hashes   []hash.Hash
-	elements uint32
+	k        uint32
+	elements uint64
+	capacity uint64
+	fpRate   float64
 }
 
-// mapToBytes converts a comparable value to bytes for hashing.
-func mapToBytes[T comparable](obj T) []byte {
-	return []byte(fmt.Sprintf("%T.%v", obj, obj))
-}
+// fixedSeed makes up the public key WithFixedKey selects.
+const fixedSeed = 1337_420
+
+// defaultBitsPerElement is the bits-per-element ratio NewBloomFilter assumes
+// when deriving capacity from a raw bit size; 10 bits give roughly a 1% rate.
+const defaultBitsPerElement = 10
 
 // NewBloomFilter creates a new type-safe bloom filter with the specified bit size.
-// The size must be greater than 0 or it will panic.
-func NewBloomFilter[T comparable](size uint32) *BloomFilter[T] {
-	if size == 0 {
-		panic("size must be greater than 0")
-	}
+// The filter is sized for size/10 elements, which fixes its hash count.
+// It is Must(New[T](Config{Bits: size}, opts...)): a zero size or invalid
+// options panic.
+func NewBloomFilter[T any](size uint64, opts ...Option) *BloomFilter[T] {
+	return Must(New[T](Config{Bits: size}, opts...))
+}
+
+// NewWithEstimates creates a bloom filter sized to hold n elements with a
+// false-positive rate of at most p. The bit size and hash count are derived
+// from n and p; invalid inputs are reported as errors.
+func NewWithEstimates[T any](n uint64, p float64, opts ...Option) (*BloomFilter[T], error) {
+	return New[T](Config{Capacity: n, FalsePositiveRate: p}, opts...)
+}
 
+func newBloomFilter[T any](p params, enc Encoder[T]) *BloomFilter[T] {
 	return &BloomFilter[T]{
-		bs:       bitset.NewBitset(size),
-		hashes:   hash.NewHashList(size),
-		elements: 0,
+		keyHasher: newKeyHasher(p.family, p.key, enc),
+		bs:        bitset.NewBitset(p.bits),
+		k:         p.k,
+		elements:  0,
+		capacity:  p.capacity,
+		fpRate:    p.fpRate,
 	}
 }
 
+// probes returns the sequence of bit positions for a key's digest.
+func (bf *BloomFilter[T]) probes(h1, h2 uint64) ihash.Probes {
+	return ihash.NewProbes(h1, h2, bf.bs.Size())
+}
+
 // Insert adds an element to the bloom filter.
 // If the element is already present (or appears to be due to hash collisions),
 // it will not be added again.
-func (bf *BloomFilter[T]) Insert(data T) {
-	if bf.Contains(data) {
-		return
+func (bf *BloomFilter[T]) Insert(data T) error {
+	return bf.insertSum(bf.sum(data))
+}
+
+func (bf *BloomFilter[T]) insertSum(h1, h2 uint64) error {
+	probes := bf.probes(h1, h2)
+	if bf.containsProbes(probes) {
+		return nil
 	}
 
 	bitset := bf.bs
 	bf.elements++
-	for _, h := range bf.hashes {
-		hashsum := h.Compute(mapToBytes(data))
-		bitset.Set(hashsum % bitset.Size())
-		bf.hashes = hash.UpdateList(bf.hashes, bf.Size(), bf.elements)
+	for i := uint32(0); i < bf.k; i++ {
+		if err := bitset.Set(probes.Next()); err != nil {
+			return fmt.Errorf("inserting key: %w", err)
+		}
 	}
+	return nil
 }
 
 // Contains checks if an element might be in the bloom filter.
 // Returns true if the element might be present (with possible false positives).
 // Returns false if the element is definitely not present.
 func (bf *BloomFilter[T]) Contains(data T) bool {
-	bitset := bf.bs
+	return bf.containsSum(bf.sum(data))
+}
+
+func (bf *BloomFilter[T]) containsSum(h1, h2 uint64) bool {
+	return bf.containsProbes(bf.probes(h1, h2))
+}
 
-	for _, h := range bf.hashes {
-		hashsum := h.Compute(mapToBytes(data))
+func (bf *BloomFilter[T]) containsProbes(probes ihash.Probes) bool {
+	bitset := bf.bs
 
-		set, _ := bitset.IsSet(hashsum % bitset.Size())
+	for i := uint32(0); i < bf.k; i++ {
+		set, _ := bitset.IsSet(probes.Next())
 		if !set {
 			return false
 		}
@@ -69,6 +107,22 @@ func (bf *BloomFilter[T]) Contains(data T) bool {
 }
 
 // Size returns the total bit size of the bloom filter.
-func (bf *BloomFilter[T]) Size() uint32 {
+func (bf *BloomFilter[T]) Size() uint64 {
 	return bf.bs.Size()
 }
+
+// HashCount returns the number of hash functions the filter applies per element.
+func (bf *BloomFilter[T]) HashCount() uint32 {
+	return bf.k
+}
+
+// Capacity returns the number of elements the filter was sized for.
+func (bf *BloomFilter[T]) Capacity() uint64 {
+	return bf.capacity
+}
+
+// FalsePositiveRate returns the false-positive rate the filter was sized for,
+// expected once Capacity elements have been inserted.
+func (bf *BloomFilter[T]) FalsePositiveRate() float64 {
+	return bf.fpRate
+}
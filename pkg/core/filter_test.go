@@ -1,6 +1,11 @@
 package core
 
 import (
+	"alex/bvs/pkg/hash"
+	"bytes"
+	"errors"
+	"math"
+	"sync"
 	"testing"
 )
 
@@ -9,9 +14,9 @@ import (
 func TestBloomFilter_New(t *testing.T) {
 	tests := []struct {
 		name      string
-		size      uint32
+		size      uint64
 		wantPanic bool
-		wantSize  uint32
+		wantSize  uint64
 	}{
 		{"size 1", 1, false, 1},
 		{"size 16", 16, false, 16},
@@ -47,7 +52,7 @@ func TestBloomFilter_New(t *testing.T) {
 func TestBloomFilter_StringType(t *testing.T) {
 	tests := []struct {
 		name         string
-		size         uint32
+		size         uint64
 		insertData   []string
 		checkData    string
 		wantContains bool
@@ -62,7 +67,9 @@ func TestBloomFilter_StringType(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			f := NewBloomFilter[string](tt.size)
+			// Filters this small only give these exact answers for a known
+			// key, so the tests here use the fixed one.
+			f := NewBloomFilter[string](tt.size, WithFixedKey())
 			for _, data := range tt.insertData {
 				f.Insert(data)
 			}
@@ -77,7 +84,7 @@ func TestBloomFilter_StringType(t *testing.T) {
 func TestBloomFilter_IntType(t *testing.T) {
 	tests := []struct {
 		name         string
-		size         uint32
+		size         uint64
 		insertData   []int
 		checkData    int
 		wantContains bool
@@ -91,7 +98,7 @@ func TestBloomFilter_IntType(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			f := NewBloomFilter[int](tt.size)
+			f := NewBloomFilter[int](tt.size, WithFixedKey())
 			for _, data := range tt.insertData {
 				f.Insert(data)
 			}
@@ -111,7 +118,7 @@ func TestBloomFilter_StructType(t *testing.T) {
 
 	tests := []struct {
 		name         string
-		size         uint32
+		size         uint64
 		insertData   []Person
 		checkData    Person
 		wantContains bool
@@ -132,7 +139,7 @@ func TestBloomFilter_StructType(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			f := NewBloomFilter[Person](tt.size)
+			f := NewBloomFilter[Person](tt.size, WithFixedKey())
 			for _, data := range tt.insertData {
 				f.Insert(data)
 			}
@@ -147,9 +154,9 @@ func TestBloomFilter_StructType(t *testing.T) {
 func TestBloomFilter_Duplicates(t *testing.T) {
 	tests := []struct {
 		name         string
-		size         uint32
+		size         uint64
 		insertData   []string
-		wantElements uint32
+		wantElements uint64
 	}{
 		{"insert same string twice", 16, []string{"hello", "hello"}, 1},
 		{"insert different strings", 32, []string{"hello", "world"}, 2},
@@ -159,7 +166,7 @@ func TestBloomFilter_Duplicates(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			f := NewBloomFilter[string](tt.size)
+			f := NewBloomFilter[string](tt.size, WithFixedKey())
 			for _, data := range tt.insertData {
 				f.Insert(data)
 			}
@@ -180,46 +187,87 @@ func TestBloomFilter_Duplicates(t *testing.T) {
 	}
 }
 
-func TestBloomFilter_HashFunctionUpdates(t *testing.T) {
+func TestBloomFilter_HashCountFixed(t *testing.T) {
 	tests := []struct {
 		name           string
-		size           uint32
+		size           uint64
 		insertSequence []string
-		wantHashCounts []int
+		wantHashCount  int
 	}{
-		{
-			"hash count decreases with elements",
-			16,
-			[]string{"first", "second"},
-			[]int{11, 5},
-		},
-		{
-			"single insertion",
-			16,
-			[]string{"single"},
-			[]int{11},
-		},
-		{
-			"multiple insertions",
-			32,
-			[]string{"a", "b", "c", "d"},
-			[]int{22, 11, 7, 5},
-		},
+		{"hash count stays fixed", 16, []string{"first", "second"}, 11},
+		{"single insertion", 16, []string{"single"}, 11},
+		{"multiple insertions", 32, []string{"a", "b", "c", "d"}, 7},
+		{"large filter", 1024, []string{"a", "b", "c", "d", "e", "f"}, 7},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			f := NewBloomFilter[string](tt.size)
+			f := NewBloomFilter[string](tt.size, WithFixedKey())
 			for i, data := range tt.insertSequence {
 				f.Insert(data)
-				if len(f.hashes) != tt.wantHashCounts[i] {
-					t.Errorf("after insert %d: hash count = %d, want %d", i, len(f.hashes), tt.wantHashCounts[i])
+				if int(f.k) != tt.wantHashCount {
+					t.Errorf("after insert %d: hash count = %d, want %d", i, f.k, tt.wantHashCount)
 				}
 			}
 		})
 	}
 }
 
+func TestBloomFilter_NoFalseNegatives(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   func() *BloomFilter[int]
+		numItems int
+	}{
+		{"within capacity", func() *BloomFilter[int] { return NewBloomFilter[int](1 << 16) }, 5000},
+		{"overfilled", func() *BloomFilter[int] { return NewBloomFilter[int](1024) }, 5000},
+		{"sized by estimates", func() *BloomFilter[int] {
+			f, _ := NewWithEstimates[int](2000, 0.01)
+			return f
+		}, 2000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.filter()
+			k := f.HashCount()
+			for i := 0; i < tt.numItems; i++ {
+				f.Insert(i)
+			}
+			if f.HashCount() != k {
+				t.Errorf("HashCount() = %d after inserts, want %d", f.HashCount(), k)
+			}
+			for i := 0; i < tt.numItems; i++ {
+				if !f.Contains(i) {
+					t.Fatalf("Contains(%d) = false after insert, want true", i)
+				}
+			}
+		})
+	}
+}
+
+func TestBloomFilter_FalsePositiveRate(t *testing.T) {
+	const n, p = 2000, 0.01
+	f, err := NewWithEstimates[int](n, p)
+	if err != nil {
+		t.Fatalf("NewWithEstimates() error = %v", err)
+	}
+	for i := 0; i < n; i++ {
+		f.Insert(i)
+	}
+
+	falsePositives := 0
+	const probes = 20000
+	for i := n; i < n+probes; i++ {
+		if f.Contains(i) {
+			falsePositives++
+		}
+	}
+	if got := float64(falsePositives) / probes; got > 2*p {
+		t.Errorf("false-positive rate = %g, want at most %g", got, 2*p)
+	}
+}
+
 func TestBloomFilter_EmptyFilter(t *testing.T) {
 	t.Run("empty string filter", func(t *testing.T) {
 		f := NewBloomFilter[string](16)
@@ -239,7 +287,7 @@ func TestBloomFilter_EmptyFilter(t *testing.T) {
 func TestBloomFilter_LargeDataset(t *testing.T) {
 	tests := []struct {
 		name     string
-		size     uint32
+		size     uint64
 		numItems int
 	}{
 		{"100 items in size 1024", 1024, 100},
@@ -248,7 +296,7 @@ func TestBloomFilter_LargeDataset(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			f := NewBloomFilter[string](tt.size)
+			f := NewBloomFilter[string](tt.size, WithFixedKey())
 			
 			// Insert items using unique strings
 			for i := 0; i < tt.numItems; i++ {
@@ -321,3 +369,157 @@ func BenchmarkBloomFilter_Struct(b *testing.B) {
 		f.Contains(p)
 	}
 }
+
+func TestBloomFilter_NewWithEstimates(t *testing.T) {
+	tests := []struct {
+		name          string
+		n             uint64
+		p             float64
+		wantErr       error
+		wantSize      uint64
+		wantHashCount uint32
+	}{
+		{"1000 items at 1%", 1000, 0.01, nil, 9586, 7},
+		{"1 item at 50%", 1, 0.5, nil, 2, 1},
+		{"10000 items at 0.1%", 10000, 0.001, nil, 143776, 10},
+		{"zero capacity", 0, 0.01, ErrInvalidCapacity, 0, 0},
+		{"zero rate", 1000, 0, ErrInvalidFalsePositiveRate, 0, 0},
+		{"rate of one", 1000, 1, ErrInvalidFalsePositiveRate, 0, 0},
+		{"negative rate", 1000, -0.5, ErrInvalidFalsePositiveRate, 0, 0},
+		{"NaN rate", 1000, math.NaN(), ErrInvalidFalsePositiveRate, 0, 0},
+		{"too large", 1 << 62, 1e-9, ErrFilterTooLarge, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewWithEstimates[string](tt.n, tt.p)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewWithEstimates() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if f != nil {
+					t.Errorf("NewWithEstimates() = %v, want nil", f)
+				}
+				return
+			}
+			if f.Size() != tt.wantSize {
+				t.Errorf("Size() = %d, want %d", f.Size(), tt.wantSize)
+			}
+			if f.HashCount() != tt.wantHashCount {
+				t.Errorf("HashCount() = %d, want %d", f.HashCount(), tt.wantHashCount)
+			}
+			if f.Capacity() != tt.n {
+				t.Errorf("Capacity() = %d, want %d", f.Capacity(), tt.n)
+			}
+			if f.FalsePositiveRate() != tt.p {
+				t.Errorf("FalsePositiveRate() = %g, want %g", f.FalsePositiveRate(), tt.p)
+			}
+		})
+	}
+}
+
+func TestBloomFilter_HashFamily(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantFamily string
+		wantErr    error
+	}{
+		{"default", nil, hash.SipHash, nil},
+		{"siphash", []Option{WithHashFamily(hash.SipHash)}, hash.SipHash, nil},
+		{"xxhash64", []Option{WithHashFamily(hash.XXHash64)}, hash.XXHash64, nil},
+		{"murmur3", []Option{WithHashFamily(hash.Murmur3)}, hash.Murmur3, nil},
+		{"fnv1a", []Option{WithHashFamily(hash.FNV1a)}, hash.FNV1a, nil},
+		{"unknown", []Option{WithHashFamily("md5")}, "", ErrUnknownHashFamily},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewWithEstimates[int](1000, 0.01, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewWithEstimates() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if f.HashFamily() != tt.wantFamily {
+				t.Errorf("HashFamily() = %q, want %q", f.HashFamily(), tt.wantFamily)
+			}
+			for i := 0; i < 1000; i++ {
+				f.Insert(i)
+			}
+			for i := 0; i < 1000; i++ {
+				if !f.Contains(i) {
+					t.Fatalf("Contains(%d) = false after insert, want true", i)
+				}
+			}
+		})
+	}
+}
+
+func TestBloomFilter_Key(t *testing.T) {
+	insert := func(f *BloomFilter[string]) *BloomFilter[string] {
+		for _, s := range []string{"alpha", "beta", "gamma"} {
+			f.Insert(s)
+		}
+		return f
+	}
+
+	t.Run("default keys are random", func(t *testing.T) {
+		a := insert(NewBloomFilter[string](1024))
+		b := insert(NewBloomFilter[string](1024))
+		if a.Key() == b.Key() || bytes.Equal(a.bs.List(), b.bs.List()) {
+			t.Error("two filters with the default key hash alike")
+		}
+	})
+
+	t.Run("fixed key is shared", func(t *testing.T) {
+		a := insert(NewBloomFilter[string](1024, WithFixedKey()))
+		b := insert(NewBloomFilter[string](1024, WithFixedKey()))
+		if a.Key() != b.Key() || !bytes.Equal(a.bs.List(), b.bs.List()) {
+			t.Error("filters with the fixed key differ")
+		}
+	})
+
+	t.Run("secret keys differ", func(t *testing.T) {
+		a := insert(NewBloomFilter[string](1024, WithSecretKey()))
+		b := insert(NewBloomFilter[string](1024, WithSecretKey()))
+		if a.Key() == b.Key() {
+			t.Error("WithSecretKey() produced the same key twice")
+		}
+		if bytes.Equal(a.bs.List(), b.bs.List()) {
+			t.Error("filters with different secret keys set the same bits")
+		}
+	})
+
+	t.Run("exported key reproduces filter", func(t *testing.T) {
+		a := insert(NewBloomFilter[string](1024, WithSecretKey()))
+		b := insert(NewBloomFilter[string](1024, WithKey(a.Key())))
+		if !bytes.Equal(a.bs.List(), b.bs.List()) {
+			t.Error("filter built with exported key sets different bits")
+		}
+		if !b.Contains("beta") {
+			t.Error("Contains(beta) = false, want true")
+		}
+	})
+}
+
+func TestBloomFilter_ConcurrentContains(t *testing.T) {
+	f := NewBloomFilter[string](1024)
+	f.Insert("present")
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				if !f.Contains("present") || f.Contains("a much longer absent key") {
+					t.Error("Contains() gave a wrong answer under concurrent lookups")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
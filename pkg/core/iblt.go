@@ -0,0 +1,335 @@
+package core
+
+import (
+	"alex/bvs/internal/fuse"
+	"alex/bvs/pkg/hash"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+const (
+	defaultIBLTHashCount = 4
+
+	// ibltOverhead is the number of cells per key a table is sized for; with
+	// four hash functions peeling succeeds with high probability above about
+	// 1.3 cells per key.
+	ibltOverhead = 1.5
+	// ibltSlack is added to every subtable. Small tables mostly fail when a
+	// few keys share all their cells, which the slack makes rare.
+	ibltSlack = 8
+
+	// ibltCellBits is the serialized size of a cell.
+	ibltCellBits = 3 * 64
+	// ibltCheckTweak separates the seed of the check hash from the key's.
+	ibltCheckTweak = 0x9e3779b97f4a7c15
+)
+
+// IBLT is an invertible Bloom lookup table over uint64 keys. Each key is
+// added to one cell in each of HashCount subtables; a cell keeps the number
+// of keys added, the XOR of the keys and the XOR of a check hash of each.
+// Unlike a filter, an IBLT can list its keys as long as it holds few enough
+// of them, by repeatedly peeling cells that hold exactly one key.
+// To reconcile two sets, each side builds a table with the same Config,
+// one is sent to the other, and Subtract followed by Decode yields the keys
+// only in either set, at a cost proportional to the size of the difference
+// rather than of the sets. A StrataEstimator sizes the table when the
+// difference is not known in advance.
+// see Goodrich & Mitzenmacher, "Invertible Bloom Lookup Tables"
+// and Eppstein et al., "What's the Difference? Efficient Set Reconciliation without Prior Context"
+// An IBLT is not safe for concurrent use.
+type IBLT struct {
+	keyHasher[uint64]
+	check    hash.Hasher
+	cells    []ibltCell
+	slice    uint64
+	k        uint32
+	capacity uint64
+}
+
+// ibltCell is one cell of an IBLT.
+type ibltCell struct {
+	count   int64
+	keySum  uint64
+	hashSum uint64
+}
+
+// IBLTConfig describes an invertible Bloom lookup table.
+type IBLTConfig struct {
+	// Capacity is the number of keys the table must be able to list, which
+	// after Subtract is the size of the set difference. It must be set.
+	Capacity uint64
+	// HashCount is the number of cells each key is added to. Zero selects 4.
+	HashCount uint32
+}
+
+// NewIBLT creates an invertible Bloom lookup table described by cfg. Only
+// the hashing options apply: WithEncoder, WithHashFamily, WithKey,
+// WithSecretKey and WithFixedKey.
+func NewIBLT(cfg IBLTConfig, opts ...Option) (*IBLT, error) {
+	if cfg.Capacity == 0 {
+		return nil, ErrInvalidCapacity
+	}
+	k := cfg.HashCount
+	if k == 0 {
+		k = defaultIBLTHashCount
+	}
+	if k > maxHashCount {
+		return nil, fmt.Errorf("%w: %d exceeds %d", ErrInvalidHashCount, k, maxHashCount)
+	}
+
+	slice := math.Ceil(ibltOverhead*float64(cfg.Capacity)/float64(k)) + ibltSlack
+	if exceedsMaxBits(slice * float64(k) * ibltCellBits) {
+		return nil, fmt.Errorf("%w: %d keys", ErrFilterTooLarge, cfg.Capacity)
+	}
+
+	kh, err := keyHasherFor[uint64](optionsConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+	return newIBLT(kh, k, uint64(slice), cfg.Capacity), nil
+}
+
+func newIBLT(kh keyHasher[uint64], k uint32, slice, capacity uint64) *IBLT {
+	return &IBLT{
+		keyHasher: kh,
+		check:     ibltCheckHasher(kh.family, kh.key),
+		cells:     make([]ibltCell, uint64(k)*slice),
+		slice:     slice,
+		k:         k,
+		capacity:  capacity,
+	}
+}
+
+// ibltCheckHasher returns the hasher for the check sums, seeded apart from
+// the one that picks cells so that the two are independent.
+func ibltCheckHasher(family hash.Family, key hash.Key) hash.Hasher {
+	seed0, seed1 := key.Seeds()
+	return family.New(seed0^ibltCheckTweak, seed1^ibltCheckTweak)
+}
+
+// ibltHashes is the digest that picks a key's cells, and its check hash.
+type ibltHashes struct {
+	h1, h2, check uint64
+}
+
+func (t *IBLT) hashes(key uint64) ibltHashes {
+	buf := keyBufs.Get().(*[]byte)
+	*buf = t.enc.AppendKey((*buf)[:0], key)
+	h1, h2 := t.hasher.Sum128(*buf)
+	check, _ := t.check.Sum128(*buf)
+	keyBufs.Put(buf)
+	return ibltHashes{h1, h2, check}
+}
+
+// cell returns the index of the key's cell in subtable i. Unlike the probes
+// of a bloom filter each index is mixed separately: with plain double
+// hashing, keys that share two cells tend to share the third, and such pairs
+// can never be peeled.
+func (t *IBLT) cell(h ibltHashes, i uint64) uint64 {
+	index, _ := bits.Mul64(fuse.Mix(h.h1, i*h.h2), t.slice)
+	return i*t.slice + index
+}
+
+// apply adds the key to its cells count times; a negative count removes it.
+func (t *IBLT) apply(cells []ibltCell, key uint64, h ibltHashes, count int64) {
+	for i := range uint64(t.k) {
+		c := &cells[t.cell(h, i)]
+		c.count += count
+		c.keySum ^= key
+		c.hashSum ^= h.check
+	}
+}
+
+// pure reports whether a cell holds exactly one key, added or removed.
+func (t *IBLT) pure(c ibltCell) bool {
+	return (c.count == 1 || c.count == -1) && t.hashes(c.keySum).check == c.hashSum
+}
+
+// Insert adds key to the table.
+func (t *IBLT) Insert(key uint64) {
+	t.apply(t.cells, key, t.hashes(key), 1)
+}
+
+// Delete removes key from the table. Deleting a key that was never inserted
+// is allowed; Decode then lists it among the keys of the other side.
+func (t *IBLT) Delete(key uint64) {
+	t.apply(t.cells, key, t.hashes(key), -1)
+}
+
+// Compatible reports whether other can be subtracted from t: both must have
+// the same number of cells, hash count, hash family, key and key encoder.
+func (t *IBLT) Compatible(other *IBLT) error {
+	switch {
+	case len(t.cells) != len(other.cells):
+		return &IncompatibleError{"cells", len(t.cells), len(other.cells)}
+	case t.k != other.k:
+		return &IncompatibleError{"hash count", t.k, other.k}
+	}
+	return t.keyHasher.compatible(&other.keyHasher)
+}
+
+// Subtract removes every key of other from t. Keys in both tables cancel
+// out, leaving t holding the keys only in t as inserted and those only in
+// other as deleted. Clone t first to keep it.
+func (t *IBLT) Subtract(other *IBLT) error {
+	if err := t.Compatible(other); err != nil {
+		return err
+	}
+	for i, c := range other.cells {
+		t.cells[i].count -= c.count
+		t.cells[i].keySum ^= c.keySum
+		t.cells[i].hashSum ^= c.hashSum
+	}
+	return nil
+}
+
+// Clone returns an independent copy of the table.
+func (t *IBLT) Clone() *IBLT {
+	clone := *t
+	clone.keyHasher = newKeyHasher(t.family, t.key, t.enc)
+	clone.cells = slices.Clone(t.cells)
+	return &clone
+}
+
+// Decode lists the keys in the table, leaving it unchanged: inserted are
+// the keys inserted and not deleted, deleted those deleted and not inserted.
+// After Subtract they are the keys only in t and only in other. Both lists
+// are sorted.
+// If the table holds too many keys, Decode returns the keys it recovered
+// along with an error wrapping ErrDecodeFailed.
+func (t *IBLT) Decode() (inserted, deleted []uint64, err error) {
+	cells := slices.Clone(t.cells)
+	var queue []uint64
+	for i, c := range cells {
+		if t.pure(c) {
+			queue = append(queue, uint64(i))
+		}
+	}
+
+	for len(queue) > 0 {
+		i := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		c := cells[i]
+		if !t.pure(c) {
+			continue
+		}
+		if c.count > 0 {
+			inserted = append(inserted, c.keySum)
+		} else {
+			deleted = append(deleted, c.keySum)
+		}
+
+		h := t.hashes(c.keySum)
+		for j := range uint64(t.k) {
+			p := t.cell(h, j)
+			cells[p].count -= c.count
+			cells[p].keySum ^= c.keySum
+			cells[p].hashSum ^= h.check
+			if t.pure(cells[p]) {
+				queue = append(queue, p)
+			}
+		}
+	}
+
+	slices.Sort(inserted)
+	slices.Sort(deleted)
+	var left int
+	for _, c := range cells {
+		if c != (ibltCell{}) {
+			left++
+		}
+	}
+	if left != 0 {
+		return inserted, deleted, fmt.Errorf("%w: %d cells left after listing %d keys", ErrDecodeFailed, left, len(inserted)+len(deleted))
+	}
+	return inserted, deleted, nil
+}
+
+// Cells returns the number of cells.
+func (t *IBLT) Cells() uint64 {
+	return uint64(len(t.cells))
+}
+
+// HashCount returns the number of cells each key is added to.
+func (t *IBLT) HashCount() uint32 {
+	return t.k
+}
+
+// Capacity returns the number of keys the table was sized to list.
+func (t *IBLT) Capacity() uint64 {
+	return t.capacity
+}
+
+// Size returns the total bit size of the cells.
+func (t *IBLT) Size() uint64 {
+	return uint64(len(t.cells)) * ibltCellBits
+}
+
+func (t *IBLT) header() header {
+	return header{
+		layout:   layoutIBLT,
+		encoder:  t.enc.ID(),
+		family:   t.family.Name(),
+		key:      t.key,
+		k:        t.k,
+		bits:     t.Size(),
+		capacity: t.capacity,
+	}
+}
+
+// appendCells appends the serialized form of cells to b.
+func appendCells(b []byte, cells []ibltCell) []byte {
+	for _, c := range cells {
+		b = binary.LittleEndian.AppendUint64(b, uint64(c.count))
+		b = binary.LittleEndian.AppendUint64(b, c.keySum)
+		b = binary.LittleEndian.AppendUint64(b, c.hashSum)
+	}
+	return b
+}
+
+// readCells fills cells from their serialized form in b.
+func readCells(cells []ibltCell, b []byte) {
+	for i := range cells {
+		cells[i] = ibltCell{
+			count:   int64(binary.LittleEndian.Uint64(b[24*i:])),
+			keySum:  binary.LittleEndian.Uint64(b[24*i+8:]),
+			hashSum: binary.LittleEndian.Uint64(b[24*i+16:]),
+		}
+	}
+}
+
+// WriteTo writes the table to w in the serialized format, with the IBLT
+// layout.
+func (t *IBLT) WriteTo(w io.Writer) (int64, error) {
+	return writeFilter(w, t.header(), appendCells(nil, t.cells))
+}
+
+// ReadFrom replaces the table with one read from r in the serialized format.
+// The encoder is chosen as for BloomFilter.ReadFrom.
+func (t *IBLT) ReadFrom(r io.Reader) (int64, error) {
+	d, n, err := readFilter(r, layoutIBLT, t.enc)
+	if err != nil {
+		return n, err
+	}
+	if d.bits%(uint64(d.k)*ibltCellBits) != 0 {
+		return n, fmt.Errorf("%w: bit size %d is not a whole number of cells per hash", ErrInvalidFormat, d.bits)
+	}
+
+	*t = *newIBLT(d.keyHasher, d.k, d.bits/ibltCellBits/uint64(d.k), d.capacity)
+	readCells(t.cells, d.payload)
+	return n, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (t *IBLT) MarshalBinary() ([]byte, error) {
+	return marshal(64+len(t.family.Name())+int(t.Size()/8), t.WriteTo)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. See ReadFrom.
+func (t *IBLT) UnmarshalBinary(data []byte) error {
+	return unmarshal(data, t.ReadFrom)
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestNewIBLT(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       IBLTConfig
+		wantErr   error
+		wantCells uint64
+	}{
+		{"default hash count", IBLTConfig{Capacity: 100}, nil, 184},
+		{"custom hash count", IBLTConfig{Capacity: 100, HashCount: 3}, nil, 174},
+		{"single key", IBLTConfig{Capacity: 1}, nil, 36},
+		{"no capacity", IBLTConfig{}, ErrInvalidCapacity, 0},
+		{"too many hashes", IBLTConfig{Capacity: 10, HashCount: maxHashCount + 1}, ErrInvalidHashCount, 0},
+		{"too large", IBLTConfig{Capacity: 1 << 62}, ErrFilterTooLarge, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb, err := NewIBLT(tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewIBLT() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tb.Cells() != tt.wantCells {
+				t.Errorf("Cells() = %d, want %d", tb.Cells(), tt.wantCells)
+			}
+			if tb.Cells()%uint64(tb.HashCount()) != 0 {
+				t.Errorf("Cells() = %d, not a multiple of HashCount() %d", tb.Cells(), tb.HashCount())
+			}
+		})
+	}
+}
+
+// reconcile builds tables from two sets sharing common keys and returns what
+// Decode recovers from their difference.
+func reconcile(t *testing.T, cfg IBLTConfig, common, onlyA, onlyB []uint64) (a, b []uint64, err error) {
+	t.Helper()
+	// A fixed key keeps the rare decode failures from making tests flaky.
+	ta := Must(NewIBLT(cfg, WithFixedKey()))
+	tb := Must(NewIBLT(cfg, WithFixedKey()))
+	for _, key := range common {
+		ta.Insert(key)
+		tb.Insert(key)
+	}
+	for _, key := range onlyA {
+		ta.Insert(key)
+	}
+	for _, key := range onlyB {
+		tb.Insert(key)
+	}
+	if err := ta.Subtract(tb); err != nil {
+		t.Fatalf("Subtract() error = %v", err)
+	}
+	return ta.Decode()
+}
+
+// randomKeys returns n distinct random keys.
+func randomKeys(r *rand.Rand, n int) []uint64 {
+	seen := make(map[uint64]bool, n)
+	keys := make([]uint64, 0, n)
+	for len(keys) < n {
+		if key := r.Uint64(); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestIBLT_Reconcile(t *testing.T) {
+	for _, d := range []int{1, 2, 5, 20, 100, 1000} {
+		var failures int
+		for trial := range 50 {
+			r := rand.New(rand.NewPCG(uint64(d), uint64(trial)))
+			keys := randomKeys(r, 2000+d)
+			common, diff := keys[:2000], keys[2000:]
+			split := r.IntN(d + 1)
+			onlyA, onlyB := diff[:split], diff[split:]
+
+			a, b, err := reconcile(t, IBLTConfig{Capacity: uint64(d)}, common, onlyA, onlyB)
+			if err != nil {
+				if !errors.Is(err, ErrDecodeFailed) {
+					t.Fatalf("d=%d: Decode() error = %v, want %v", d, err, ErrDecodeFailed)
+				}
+				failures++
+				continue
+			}
+			slices.Sort(onlyA)
+			slices.Sort(onlyB)
+			if !slices.Equal(a, onlyA) || !slices.Equal(b, onlyB) {
+				t.Fatalf("d=%d: Decode() = %d, %d keys, want %d, %d", d, len(a), len(b), len(onlyA), len(onlyB))
+			}
+		}
+		if failures > 2 {
+			t.Errorf("d=%d: %d of 50 decodes failed, want at most 2", d, failures)
+		}
+	}
+}
+
+func TestIBLT_DecodeFailed(t *testing.T) {
+	r := rand.New(rand.NewPCG(5, 6))
+	keys := randomKeys(r, 500)
+	a, b, err := reconcile(t, IBLTConfig{Capacity: 50}, nil, keys, nil)
+	if !errors.Is(err, ErrDecodeFailed) {
+		t.Fatalf("Decode() error = %v, want %v", err, ErrDecodeFailed)
+	}
+	if len(b) != 0 {
+		t.Errorf("Decode() listed %d deleted keys, want 0", len(b))
+	}
+	for _, key := range a {
+		if !slices.Contains(keys, key) {
+			t.Fatalf("Decode() listed %d, which was never inserted", key)
+		}
+	}
+}
+
+func TestIBLT_InsertDelete(t *testing.T) {
+	tb := Must(NewIBLT(IBLTConfig{Capacity: 10}))
+	for key := range uint64(100) {
+		tb.Insert(key)
+	}
+	for key := range uint64(100) {
+		if key != 42 {
+			tb.Delete(key)
+		}
+	}
+	tb.Delete(1000)
+
+	inserted, deleted, err := tb.Decode()
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !slices.Equal(inserted, []uint64{42}) || !slices.Equal(deleted, []uint64{1000}) {
+		t.Errorf("Decode() = %v, %v, want [42], [1000]", inserted, deleted)
+	}
+	if _, _, err := tb.Decode(); err != nil {
+		t.Errorf("second Decode() error = %v, want the table unchanged", err)
+	}
+}
+
+func TestIBLT_Incompatible(t *testing.T) {
+	a := Must(NewIBLT(IBLTConfig{Capacity: 10}))
+	var ie *IncompatibleError
+	if err := a.Subtract(Must(NewIBLT(IBLTConfig{Capacity: 20}, WithKey(a.Key())))); !errors.As(err, &ie) || ie.Field != "cells" {
+		t.Errorf("Subtract() error = %v, want IncompatibleError on cells", err)
+	}
+	if err := a.Subtract(Must(NewIBLT(IBLTConfig{Capacity: 10}))); !errors.As(err, &ie) || ie.Field != "key" {
+		t.Errorf("Subtract() error = %v, want IncompatibleError on key", err)
+	}
+}
+
+func TestIBLT_MarshalRoundTrip(t *testing.T) {
+	// Decoding fails for a small share of hash keys; a fixed one keeps this
+	// test deterministic.
+	a := Must(NewIBLT(IBLTConfig{Capacity: 20}, WithFixedKey()))
+	b := Must(NewIBLT(IBLTConfig{Capacity: 20}, WithFixedKey()))
+	for key := range uint64(1000) {
+		a.Insert(key)
+		b.Insert(key + 10)
+	}
+
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	var received IBLT
+	if err := received.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if received.Cells() != b.Cells() || received.HashCount() != b.HashCount() || received.Capacity() != 20 {
+		t.Errorf("round trip changed parameters: %d cells, k=%d, capacity %d", received.Cells(), received.HashCount(), received.Capacity())
+	}
+	if err := a.Subtract(&received); err != nil {
+		t.Fatalf("Subtract() error = %v", err)
+	}
+	onlyA, onlyB, err := a.Decode()
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !slices.Equal(onlyA, []uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}) || len(onlyB) != 10 || onlyB[0] != 1000 {
+		t.Errorf("Decode() = %v, %v, want 0..9 and 1000..1009", onlyA, onlyB)
+	}
+
+	bloom, _ := Must(New[uint64](Config{Bits: 1024})).MarshalBinary()
+	if err := received.UnmarshalBinary(bloom); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("UnmarshalBinary(other layout) error = %v, want %v", err, ErrInvalidFormat)
+	}
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"alex/bvs/pkg/hash"
+	"sync"
+)
+
+// keyHasher turns keys into digests: it encodes a key with the filter's
+// Encoder and hashes the bytes once with the filter's keyed hasher. Every
+// filter type embeds one, so they share encoding, hash families and keys.
+type keyHasher[T any] struct {
+	family hash.Family
+	key    hash.Key
+	hasher hash.Hasher
+	enc    Encoder[T]
+}
+
+// keyBufs holds the buffers keys are encoded into. They are pooled rather
+// than kept in the filter so that concurrent lookups share no state.
+var keyBufs = sync.Pool{
+	New: func() any { return new([]byte) },
+}
+
+func newKeyHasher[T any](family hash.Family, key hash.Key, enc Encoder[T]) keyHasher[T] {
+	return keyHasher[T]{
+		family: family,
+		key:    key,
+		hasher: hash.NewKeyed(family, key),
+		enc:    enc,
+	}
+}
+
+// sum encodes data into a pooled buffer and returns its 128-bit digest.
+func (kh *keyHasher[T]) sum(data T) (uint64, uint64) {
+	buf := keyBufs.Get().(*[]byte)
+	*buf = kh.enc.AppendKey((*buf)[:0], data)
+	h1, h2 := kh.hasher.Sum128(*buf)
+	keyBufs.Put(buf)
+	return h1, h2
+}
+
+// rngSeeds derives the seeds of a filter's random generator from its key.
+// The generator's choices can be observed and PCG is invertible, so the key
+// is passed through SipHash, a keyed PRF, rather than used directly: the
+// seeds reveal nothing about it, whatever family hashes the filter's keys.
+func rngSeeds(key hash.Key) (uint64, uint64) {
+	sip, _ := hash.Lookup(hash.SipHash)
+	return hash.NewKeyed(sip, key).Sum128([]byte("bvs rng seed"))
+}
+
+// compatible reports whether other hashes keys like kh: both must have the
+// same hash family, key and key encoder. The keys are never shown, as they
+// may be secret.
+func (kh *keyHasher[T]) compatible(other *keyHasher[T]) error {
+	switch {
+	case kh.family.Name() != other.family.Name():
+		return &IncompatibleError{"hash family", kh.family.Name(), other.family.Name()}
+	case kh.key != other.key:
+		return &IncompatibleError{"key", "(redacted)", "(redacted)"}
+	case kh.enc.ID() != other.enc.ID():
+		return &IncompatibleError{"encoder", kh.enc.ID(), other.enc.ID()}
+	}
+	return nil
+}
+
+// Encoder returns the encoder the filter uses for keys.
+func (kh *keyHasher[T]) Encoder() Encoder[T] {
+	return kh.enc
+}
+
+// HashFamily returns the name of the hash family the filter was built with.
+func (kh *keyHasher[T]) HashFamily() string {
+	return kh.family.Name()
+}
+
+// Key returns the key the filter's hasher is seeded from. Passing it to
+// WithKey reproduces the filter's bit positions; keep it secret unless the
+// filter was built with WithFixedKey.
+func (kh *keyHasher[T]) Key() hash.Key {
+	return kh.key
+}
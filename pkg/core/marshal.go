@@ -0,0 +1,334 @@
+package core
+
+import (
+	"alex/bvs/internal/bitset"
+	"alex/bvs/pkg/hash"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// Serialized filters use the following layout, all integers little-endian:
+//
+//	offset  size  field
+//	0       4     magic "BLSM"
+//	4       1     format version (1)
+//	5       1     layout (Layout)
+//	6       1     key encoder id (EncoderID)
+//	7       1     hash family name length L
+//	8       L     hash family name
+//	8+L     16    hash key
+//	24+L    4     hash count k
+//	28+L    8     bit size m
+//	36+L    8     inserted count n
+//	44+L    8     capacity
+//	52+L    8     target false-positive rate (IEEE 754)
+//	60+L    B     payload, (m+7)/8 bytes
+//	60+L+B  4     CRC-32C of all preceding bytes
+//
+// For the bloom filter layouts the payload is the bitset. For the binary fuse
+// layouts it is the 8-byte seed followed by the fingerprints, and for the
+// IBLT layouts the cells, each a count, key sum and hash sum of 8 bytes. For
+// the Bloomier layout it is the 8-byte seed, the value width in bits as one
+// byte and the packed values as 8-byte words. m is the payload size in bits.
+//
+// Bloom filter payloads are only meaningful with the probe positions of
+// internal/hash.Probes, which maps each position onto [0, m) by
+// multiply-shift. Changing that derivation needs a new format version.
+const (
+	formatMagic   = "BLSM"
+	formatVersion = 1
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// header holds the serialized parameters of a filter.
+type header struct {
+	layout   Layout
+	encoder  EncoderID
+	family   string
+	key      hash.Key
+	k        uint32
+	bits     uint64
+	count    uint64
+	capacity uint64
+	fpRate   float64
+}
+
+func (h header) appendTo(b []byte) []byte {
+	b = append(b, formatMagic...)
+	b = append(b, formatVersion, byte(h.layout), byte(h.encoder), byte(len(h.family)))
+	b = append(b, h.family...)
+	b = append(b, h.key[:]...)
+	b = binary.LittleEndian.AppendUint32(b, h.k)
+	b = binary.LittleEndian.AppendUint64(b, h.bits)
+	b = binary.LittleEndian.AppendUint64(b, h.count)
+	b = binary.LittleEndian.AppendUint64(b, h.capacity)
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(h.fpRate))
+	return b
+}
+
+func readHeader(r io.Reader) (header, error) {
+	var h header
+
+	var fixed [8]byte
+	if _, err := io.ReadFull(r, fixed[:]); err != nil {
+		return h, fmt.Errorf("%w: reading header: %w", ErrInvalidFormat, unexpectedEOF(err))
+	}
+	if string(fixed[:4]) != formatMagic {
+		return h, fmt.Errorf("%w: bad magic %q", ErrInvalidFormat, fixed[:4])
+	}
+	if fixed[4] != formatVersion {
+		return h, fmt.Errorf("%w: version %d, want %d", ErrUnsupportedVersion, fixed[4], formatVersion)
+	}
+	h.layout = Layout(fixed[5])
+	h.encoder = EncoderID(fixed[6])
+
+	rest := make([]byte, int(fixed[7])+52)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return h, fmt.Errorf("%w: reading header: %w", ErrInvalidFormat, unexpectedEOF(err))
+	}
+	h.family = string(rest[:fixed[7]])
+	rest = rest[fixed[7]:]
+	copy(h.key[:], rest[:16])
+	h.k = binary.LittleEndian.Uint32(rest[16:])
+	h.bits = binary.LittleEndian.Uint64(rest[20:])
+	h.count = binary.LittleEndian.Uint64(rest[28:])
+	h.capacity = binary.LittleEndian.Uint64(rest[36:])
+	h.fpRate = math.Float64frombits(binary.LittleEndian.Uint64(rest[44:]))
+
+	if h.k == 0 || h.k > maxHashCount {
+		return h, fmt.Errorf("%w: hash count %d", ErrInvalidFormat, h.k)
+	}
+	if h.bits == 0 || h.bits > maxBits {
+		return h, fmt.Errorf("%w: bit size %d", ErrInvalidFormat, h.bits)
+	}
+	return h, nil
+}
+
+// readBytes reads exactly n bytes, growing the buffer as data arrives so a
+// corrupted length cannot force a huge allocation up front.
+func readBytes(r io.Reader, n uint64) ([]byte, error) {
+	const chunk = 1 << 20
+	var buf bytes.Buffer
+	buf.Grow(int(min(n, chunk)))
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// readChecksum reads the CRC-32C trailer and compares it to sum.
+func readChecksum(r io.Reader, sum uint32) error {
+	var trailer [4]byte
+	if _, err := io.ReadFull(r, trailer[:]); err != nil {
+		return fmt.Errorf("%w: reading checksum: %w", ErrInvalidFormat, unexpectedEOF(err))
+	}
+	if got := binary.LittleEndian.Uint32(trailer[:]); got != sum {
+		return fmt.Errorf("%w: stored %#08x, computed %#08x", ErrChecksumMismatch, got, sum)
+	}
+	return nil
+}
+
+// writeFilter writes a header, the payload and the checksum trailer to w.
+func writeFilter(w io.Writer, h header, payload ...[]byte) (int64, error) {
+	crc := crc32.New(castagnoli)
+	mw := io.MultiWriter(w, crc)
+
+	n, err := mw.Write(h.appendTo(nil))
+	total := int64(n)
+	if err != nil {
+		return total, err
+	}
+	for _, p := range payload {
+		n, err = mw.Write(p)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	n, err = w.Write(binary.LittleEndian.AppendUint32(nil, crc.Sum32()))
+	return total + int64(n), err
+}
+
+// decoded is a filter read by readFilter.
+type decoded[T any] struct {
+	header
+	keyHasher[T]
+	payload []byte
+}
+
+// bitset returns the payload of a bloom filter layout as a bitset.
+func (d *decoded[T]) bitset() (*bitset.Bitset, error) {
+	bs, err := bitset.FromBytes(d.payload, d.bits)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	return bs, nil
+}
+
+// readFilter reads a filter of the given layout from r, resolving its hash
+// family and checking the stored encoder against enc, or against the default
+// encoder for T if enc is nil. It returns the number of bytes read.
+func readFilter[T any](r io.Reader, layout Layout, enc Encoder[T]) (decoded[T], int64, error) {
+	var d decoded[T]
+	cr := &countingReader{r: r}
+	crc := crc32.New(castagnoli)
+	tr := io.TeeReader(cr, crc)
+
+	h, err := readHeader(tr)
+	if err != nil {
+		return d, cr.n, err
+	}
+	if h.layout != layout {
+		return d, cr.n, fmt.Errorf("%w: layout %d, want %d", ErrInvalidFormat, h.layout, layout)
+	}
+	payload, err := readBytes(tr, (h.bits+7)/8)
+	if err != nil {
+		return d, cr.n, fmt.Errorf("%w: reading payload: %w", ErrInvalidFormat, err)
+	}
+	if err := readChecksum(cr, crc.Sum32()); err != nil {
+		return d, cr.n, err
+	}
+
+	family, ok := hash.Lookup(h.family)
+	if !ok {
+		return d, cr.n, fmt.Errorf("%w: %q", ErrUnknownHashFamily, h.family)
+	}
+	if enc == nil {
+		enc = defaultEncoder[T]()
+	}
+	if enc.ID() != h.encoder {
+		return d, cr.n, fmt.Errorf("%w: stored encoder %d, have %d", ErrEncoderMismatch, h.encoder, enc.ID())
+	}
+
+	return decoded[T]{
+		header:    h,
+		keyHasher: newKeyHasher(family, h.key, enc),
+		payload:   payload,
+	}, cr.n, nil
+}
+
+// unmarshal decodes data with readFrom and rejects trailing bytes.
+func unmarshal(data []byte, readFrom func(io.Reader) (int64, error)) error {
+	r := bytes.NewReader(data)
+	if _, err := readFrom(r); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidFormat, r.Len())
+	}
+	return nil
+}
+
+// marshal encodes a filter with writeTo into a buffer of about size bytes.
+func marshal(size int, writeTo func(io.Writer) (int64, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(size)
+	if _, err := writeTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (bf *BloomFilter[T]) header() header {
+	return header{
+		layout:   LayoutClassic,
+		encoder:  bf.enc.ID(),
+		family:   bf.family.Name(),
+		key:      bf.key,
+		k:        bf.k,
+		bits:     bf.Size(),
+		count:    bf.elements,
+		capacity: bf.capacity,
+		fpRate:   bf.fpRate,
+	}
+}
+
+// WriteTo writes the filter to w in the serialized format.
+func (bf *BloomFilter[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeFilter(w, bf.header(), bf.bs.List())
+}
+
+// ReadFrom replaces the filter with one read from r in the serialized format.
+// A filter constructed with WithEncoder keeps its encoder; otherwise the
+// default encoder for T is used. Either way its ID must match the stored one.
+func (bf *BloomFilter[T]) ReadFrom(r io.Reader) (int64, error) {
+	d, n, err := readFilter(r, LayoutClassic, bf.enc)
+	if err != nil {
+		return n, err
+	}
+	bs, err := d.bitset()
+	if err != nil {
+		return n, err
+	}
+
+	*bf = BloomFilter[T]{
+		keyHasher: d.keyHasher,
+		bs:        bs,
+		k:         d.k,
+		elements:  d.count,
+		capacity:  d.capacity,
+		fpRate:    d.fpRate,
+	}
+	return n, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (bf *BloomFilter[T]) MarshalBinary() ([]byte, error) {
+	return marshal(64+len(bf.family.Name())+len(bf.bs.List()), bf.WriteTo)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. See ReadFrom.
+func (bf *BloomFilter[T]) UnmarshalBinary(data []byte) error {
+	return unmarshal(data, bf.ReadFrom)
+}
+
+// UnmarshalFilter decodes a filter serialized with either layout, returning
+// a *BloomFilter or a *PartitionedBloomFilter. Only the WithEncoder option
+// is used, to decode keys with a custom encoder.
+func UnmarshalFilter[T any](data []byte, opts ...Option) (Filter[T], error) {
+	enc, err := encoderFor[T](optionsConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 6 {
+		return nil, fmt.Errorf("%w: reading header: %w", ErrInvalidFormat, io.ErrUnexpectedEOF)
+	}
+
+	var f Filter[T]
+	switch layout := Layout(data[5]); layout {
+	case LayoutClassic:
+		f = &BloomFilter[T]{keyHasher: keyHasher[T]{enc: enc}}
+	case LayoutPartitioned:
+		f = &PartitionedBloomFilter[T]{keyHasher: keyHasher[T]{enc: enc}}
+	default:
+		return nil, fmt.Errorf("%w: layout %d is not a Filter", ErrInvalidFormat, layout)
+	}
+	if err := f.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"alex/bvs/pkg/hash"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"testing"
+)
+
+// resum recomputes the CRC-32C trailer after a test edits the payload.
+func resum(data []byte) []byte {
+	body := data[:len(data)-4]
+	return binary.LittleEndian.AppendUint32(body, crc32.Checksum(body, castagnoli))
+}
+
+func TestBloomFilter_MarshalRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"xxhash64", []Option{WithHashFamily(hash.XXHash64)}},
+		{"secret key", []Option{WithSecretKey()}},
+		{"fmt encoder", []Option{WithEncoder(FmtEncoder[string]())}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Must(NewWithEstimates[string](500, 0.01, tt.opts...))
+			for i := 0; i < 500; i++ {
+				f.Insert(string(rune('a'+i%26)) + string(rune(i)))
+			}
+
+			data, err := f.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error = %v", err)
+			}
+			var g BloomFilter[string]
+			if tt.opts != nil {
+				g = *Must(New[string](Config{Bits: 8}, tt.opts...))
+			}
+			if err := g.UnmarshalBinary(data); err != nil {
+				t.Fatalf("UnmarshalBinary() error = %v", err)
+			}
+
+			if g.Size() != f.Size() || g.HashCount() != f.HashCount() || g.Capacity() != f.Capacity() ||
+				g.FalsePositiveRate() != f.FalsePositiveRate() || g.HashFamily() != f.HashFamily() ||
+				g.Key() != f.Key() || g.Encoder().ID() != f.Encoder().ID() || g.elements != f.elements {
+				t.Errorf("round trip changed parameters: got %+v, want %+v", g.Stats(), f.Stats())
+			}
+			if !bytes.Equal(g.bs.List(), f.bs.List()) {
+				t.Error("round trip changed bitset")
+			}
+			for i := 0; i < 500; i++ {
+				if key := string(rune('a'+i%26)) + string(rune(i)); !g.Contains(key) {
+					t.Fatalf("Contains(%q) = false after round trip, want true", key)
+				}
+			}
+		})
+	}
+}
+
+func TestBloomFilter_MarshalRoundTrip_Sparse(t *testing.T) {
+	// Sized for one element, the optimal hash count would exceed
+	// maxHashCount and the filter could not read back its own output.
+	f := Must(New[int](Config{Bits: 1 << 20, Capacity: 1}))
+	f.Insert(42)
+
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	var g BloomFilter[int]
+	if err := g.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if g.HashCount() != maxHashCount || !g.Contains(42) {
+		t.Errorf("round trip: HashCount() = %d, Contains(42) = %v, want %d, true", g.HashCount(), g.Contains(42), maxHashCount)
+	}
+}
+
+func TestBloomFilter_WriteToReadFrom(t *testing.T) {
+	f := NewBloomFilter[int](1000)
+	for i := 0; i < 50; i++ {
+		f.Insert(i)
+	}
+
+	var buf bytes.Buffer
+	written, err := f.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	if written != int64(buf.Len()) {
+		t.Errorf("WriteTo() = %d, wrote %d bytes", written, buf.Len())
+	}
+
+	buf.WriteString("next record")
+	var g BloomFilter[int]
+	read, err := g.ReadFrom(&buf)
+	if err != nil {
+		t.Fatalf("ReadFrom() error = %v", err)
+	}
+	if read != written {
+		t.Errorf("ReadFrom() = %d, want %d", read, written)
+	}
+	if buf.String() != "next record" {
+		t.Errorf("ReadFrom() consumed past the filter, left %q", buf.String())
+	}
+	if !g.Contains(25) {
+		t.Error("Contains(25) = false after ReadFrom, want true")
+	}
+}
+
+func TestBloomFilter_UnmarshalErrors(t *testing.T) {
+	f := NewBloomFilter[string](64)
+	f.Insert("x")
+	valid, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	familyLen := int(valid[7])
+	edit := func(fn func(b []byte) []byte) []byte {
+		return fn(bytes.Clone(valid))
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"empty", nil, ErrInvalidFormat},
+		{"truncated header", valid[:20], ErrInvalidFormat},
+		{"truncated bitset", valid[:len(valid)-6], ErrInvalidFormat},
+		{"missing checksum", valid[:len(valid)-4], ErrInvalidFormat},
+		{"bad magic", edit(func(b []byte) []byte { b[0] = 'X'; return b }), ErrInvalidFormat},
+		{"future version", edit(func(b []byte) []byte { b[4] = 2; return b }), ErrUnsupportedVersion},
+		{"flipped bit", edit(func(b []byte) []byte { b[len(b)-5] ^= 1; return b }), ErrChecksumMismatch},
+		{"other layout", edit(func(b []byte) []byte { b[5] = 7; return resum(b) }), ErrInvalidFormat},
+		{"zero hash count", edit(func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[24+familyLen:], 0)
+			return resum(b)
+		}), ErrInvalidFormat},
+		{"unknown family", edit(func(b []byte) []byte { b[8] = 'X'; return resum(b) }), ErrUnknownHashFamily},
+		{"other encoder", edit(func(b []byte) []byte { b[6] = byte(EncoderInt); return resum(b) }), ErrEncoderMismatch},
+		{"trailing bytes", append(bytes.Clone(valid), 0), ErrInvalidFormat},
+		{"bit size wraps payload to empty", edit(func(b []byte) []byte {
+			binary.LittleEndian.PutUint64(b[28+familyLen:], math.MaxUint64)
+			return resum(b[:60+familyLen+4])
+		}), ErrInvalidFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g BloomFilter[string]
+			if err := g.UnmarshalBinary(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("UnmarshalBinary() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"fmt"
+	"math"
+)
+
+// IncompatibleError reports the parameter that differs between two filters
+// that were to be combined. It matches ErrIncompatible with errors.Is.
+type IncompatibleError struct {
+	Field       string
+	This, Other any
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("%v: %s differs (%v vs %v)", ErrIncompatible, e.Field, e.This, e.Other)
+}
+
+func (e *IncompatibleError) Unwrap() error {
+	return ErrIncompatible
+}
+
+// Compatible reports whether other can be combined with bf: both must have
+// the same size, hash family, key, hash count and key encoder.
+func (bf *BloomFilter[T]) Compatible(other *BloomFilter[T]) error {
+	switch {
+	case bf.Size() != other.Size():
+		return &IncompatibleError{"size", bf.Size(), other.Size()}
+	case bf.k != other.k:
+		return &IncompatibleError{"hash count", bf.k, other.k}
+	}
+	return bf.keyHasher.compatible(&other.keyHasher)
+}
+
+// Clone returns an independent copy of the filter.
+func (bf *BloomFilter[T]) Clone() *BloomFilter[T] {
+	clone := *bf
+	clone.bs = bf.bs.Clone()
+	return &clone
+}
+
+// UnionWith adds every element of other to bf.
+// Afterwards bf contains anything either filter contained.
+func (bf *BloomFilter[T]) UnionWith(other *BloomFilter[T]) error {
+	if err := bf.Compatible(other); err != nil {
+		return err
+	}
+	if err := bf.bs.Or(other.bs); err != nil {
+		return err
+	}
+	bf.elements = bf.estimatedCount(bf.elements + other.elements)
+	return nil
+}
+
+// IntersectWith keeps only the bits bf shares with other. Afterwards bf
+// contains every element both filters contained, with a false-positive rate
+// at least as high as that of a filter built from the intersection directly.
+func (bf *BloomFilter[T]) IntersectWith(other *BloomFilter[T]) error {
+	if err := bf.Compatible(other); err != nil {
+		return err
+	}
+	if err := bf.bs.And(other.bs); err != nil {
+		return err
+	}
+	bf.elements = bf.estimatedCount(min(bf.elements, other.elements))
+	return nil
+}
+
+// Union returns a new filter holding the elements of both bf and other.
+func (bf *BloomFilter[T]) Union(other *BloomFilter[T]) (*BloomFilter[T], error) {
+	result := bf.Clone()
+	if err := result.UnionWith(other); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Intersect returns a new filter holding the elements common to bf and other.
+func (bf *BloomFilter[T]) Intersect(other *BloomFilter[T]) (*BloomFilter[T], error) {
+	result := bf.Clone()
+	if err := result.IntersectWith(other); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// MergeAll returns a new filter holding the union of all filters, which must
+// be pairwise compatible. The inputs are left unchanged.
+func MergeAll[T any](filters ...*BloomFilter[T]) (*BloomFilter[T], error) {
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("%w: no filters to merge", ErrIncompatible)
+	}
+
+	result := filters[0].Clone()
+	for _, f := range filters[1:] {
+		if err := result.UnionWith(f); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// estimatedCount returns the cardinality estimate of bf's bits, used as the
+// inserted count of a combined filter, capped at bound.
+func (bf *BloomFilter[T]) estimatedCount(bound uint64) uint64 {
+	n := estimateCardinality(bf.Size(), bf.k, bf.bs.Count())
+	if math.IsInf(n, 1) || n > float64(bound) {
+		return bound
+	}
+	return uint64(math.Round(n))
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"alex/bvs/pkg/hash"
+	"errors"
+	"testing"
+)
+
+// filled returns a filter holding [from, to). All such filters share a key,
+// so they can be combined.
+func filled(from, to int) *BloomFilter[int] {
+	f := Must(New[int](Config{Capacity: 2000, FalsePositiveRate: 0.01}, WithFixedKey()))
+	for i := from; i < to; i++ {
+		f.Insert(i)
+	}
+	return f
+}
+
+func TestBloomFilter_Union(t *testing.T) {
+	a, b := filled(0, 600), filled(400, 1000)
+
+	union, err := a.Union(b)
+	if err != nil {
+		t.Fatalf("Union() error = %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		if !union.Contains(i) {
+			t.Fatalf("Contains(%d) = false after Union, want true", i)
+		}
+	}
+	if a.Contains(900) {
+		t.Error("Union() modified its receiver")
+	}
+	if n := union.Stats().Inserted; n < 950 || n > 1050 {
+		t.Errorf("Inserted = %d after Union, want about 1000", n)
+	}
+
+	if err := a.UnionWith(b); err != nil {
+		t.Fatalf("UnionWith() error = %v", err)
+	}
+	if string(a.bs.List()) != string(union.bs.List()) {
+		t.Error("UnionWith() and Union() disagree")
+	}
+}
+
+func TestBloomFilter_Intersect(t *testing.T) {
+	a, b := filled(0, 600), filled(400, 1000)
+
+	inter, err := a.Intersect(b)
+	if err != nil {
+		t.Fatalf("Intersect() error = %v", err)
+	}
+	for i := 400; i < 600; i++ {
+		if !inter.Contains(i) {
+			t.Fatalf("Contains(%d) = false after Intersect, want true", i)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < 400; i++ {
+		if inter.Contains(i) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 40 {
+		t.Errorf("%d of 400 keys only in one filter still present after Intersect", falsePositives)
+	}
+	if !a.Contains(0) {
+		t.Error("Intersect() modified its receiver")
+	}
+
+	if err := a.IntersectWith(b); err != nil {
+		t.Fatalf("IntersectWith() error = %v", err)
+	}
+	if string(a.bs.List()) != string(inter.bs.List()) {
+		t.Error("IntersectWith() and Intersect() disagree")
+	}
+}
+
+func TestMergeAll(t *testing.T) {
+	shards := []*BloomFilter[int]{filled(0, 300), filled(300, 600), filled(600, 900)}
+
+	merged, err := MergeAll(shards...)
+	if err != nil {
+		t.Fatalf("MergeAll() error = %v", err)
+	}
+	for i := 0; i < 900; i++ {
+		if !merged.Contains(i) {
+			t.Fatalf("Contains(%d) = false after MergeAll, want true", i)
+		}
+	}
+	if shards[0].Contains(899) {
+		t.Error("MergeAll() modified its first input")
+	}
+
+	if _, err := MergeAll[int](); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("MergeAll() with no filters error = %v, want %v", err, ErrIncompatible)
+	}
+}
+
+func TestBloomFilter_Incompatible(t *testing.T) {
+	base := NewBloomFilter[int](1024, WithFixedKey())
+
+	tests := []struct {
+		name      string
+		other     *BloomFilter[int]
+		wantField string
+	}{
+		{"size", NewBloomFilter[int](2048, WithFixedKey()), "size"},
+		{"hash family", NewBloomFilter[int](1024, WithFixedKey(), WithHashFamily(hash.FNV1a)), "hash family"},
+		{"key", NewBloomFilter[int](1024), "key"},
+		{"hash count", Must(New[int](Config{Bits: 1024, HashCount: 3, FixedKey: true})), "hash count"},
+		{"encoder", NewBloomFilter[int](1024, WithFixedKey(), WithEncoder(FmtEncoder[int]())), "encoder"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := map[string]func() error{
+				"UnionWith":     func() error { return base.Clone().UnionWith(tt.other) },
+				"IntersectWith": func() error { return base.Clone().IntersectWith(tt.other) },
+				"Union":         func() error { _, err := base.Union(tt.other); return err },
+				"Intersect":     func() error { _, err := base.Intersect(tt.other); return err },
+				"MergeAll":      func() error { _, err := MergeAll(base, tt.other); return err },
+			}
+			for name, check := range checks {
+				err := check()
+				var incompatible *IncompatibleError
+				if !errors.As(err, &incompatible) || !errors.Is(err, ErrIncompatible) {
+					t.Fatalf("%s() error = %v, want %v", name, err, ErrIncompatible)
+				}
+				if incompatible.Field != tt.wantField {
+					t.Errorf("%s() field = %q, want %q", name, incompatible.Field, tt.wantField)
+				}
+			}
+		})
+	}
+}
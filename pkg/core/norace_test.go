@@ -0,0 +1,6 @@
+//go:build !race
+
+package core
+
+// raceEnabled reports whether tests run under the race detector.
+const raceEnabled = false
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"alex/bvs/pkg/hash"
+	"fmt"
+	"time"
+)
+
+// Option adjusts a Config at construction.
+type Option func(*Config)
+
+// WithEncoder sets the encoder used to turn keys into bytes. The encoder's
+// key type must match the filter's.
+func WithEncoder[T any](enc Encoder[T]) Option {
+	return func(c *Config) {
+		c.encoder = enc
+	}
+}
+
+// WithHashFamily selects the registered hash family the filter hashes keys
+// with. The default is hash.SipHash.
+func WithHashFamily(name string) Option {
+	return func(c *Config) {
+		c.HashFamily = name
+	}
+}
+
+// WithKey seeds the filter's hasher from key, e.g. one exported from another
+// filter with Key, so both hash keys identically.
+func WithKey(key hash.Key) Option {
+	return func(c *Config) {
+		c.Key = &key
+	}
+}
+
+// WithSecretKey seeds the filter's hasher from a random key read from
+// crypto/rand, so its bit positions cannot be predicted from the source.
+// This is the default; the option rules out combining it with WithKey or
+// WithFixedKey. Pair it with the default SipHash family; the other families
+// are not keyed PRFs.
+func WithSecretKey() Option {
+	return func(c *Config) {
+		c.SecretKey = true
+	}
+}
+
+// WithFixedKey seeds the filter's hasher from a fixed public key instead of
+// a random one, so filters built in separate processes hash alike without
+// exchanging a key. Their bit positions are predictable: only use it for
+// keys an adversary cannot choose.
+func WithFixedKey() Option {
+	return func(c *Config) {
+		c.FixedKey = true
+	}
+}
+
+// WithClock replaces time.Now as the clock a WindowedFilter rotates by, e.g.
+// with a fake clock in tests.
+func WithClock(now func() time.Time) Option {
+	return func(c *Config) {
+		c.now = now
+	}
+}
+
+// WithValueBits sets the width in bits of each value a Bloomier stores,
+// e.g. 3 for an enum of up to 8 values.
+func WithValueBits(bits uint8) Option {
+	return func(c *Config) {
+		c.valueBits = bits
+	}
+}
+
+// encoderFor returns the configured encoder, or the default one for T.
+func encoderFor[T any](c Config) (Encoder[T], error) {
+	if c.encoder == nil {
+		return defaultEncoder[T](), nil
+	}
+	enc, ok := c.encoder.(Encoder[T])
+	if !ok {
+		var zero T
+		return nil, fmt.Errorf("%w: %T cannot encode %T", ErrEncoderMismatch, c.encoder, zero)
+	}
+	return enc, nil
+}
+
+// familyFor returns the configured hash family, or SipHash by default.
+func familyFor(c Config) (hash.Family, error) {
+	name := c.HashFamily
+	if name == "" {
+		name = hash.SipHash
+	}
+	family, ok := hash.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownHashFamily, name)
+	}
+	return family, nil
+}
+
+// keyFor returns the configured key, the fixed public key if asked for, or
+// a fresh random key.
+func keyFor(c Config) (hash.Key, error) {
+	switch {
+	case c.Key != nil && (c.SecretKey || c.FixedKey):
+		return hash.Key{}, fmt.Errorf("%w: Key excludes SecretKey and FixedKey", ErrConflictingConfig)
+	case c.SecretKey && c.FixedKey:
+		return hash.Key{}, fmt.Errorf("%w: SecretKey and FixedKey are mutually exclusive", ErrConflictingConfig)
+	case c.Key != nil:
+		return *c.Key, nil
+	case c.FixedKey:
+		return hash.KeyFromSeeds(fixedSeed, fixedSeed), nil
+	default:
+		return hash.NewKey()
+	}
+}
@@ -0,0 +1,216 @@
+package core
+
+import (
+	"alex/bvs/internal/bitset"
+	ihash "alex/bvs/internal/hash"
+	"fmt"
+	"io"
+)
+
+// PartitionedBloomFilter is a bloom filter whose bitset is split into k equal
+// slices, one per hash function: hash i only ever sets a bit in slice i. A
+// key's k positions are therefore always distinct, and each slice fills
+// independently, with a fill ratio that can be reported on its own.
+// At equal size its false-positive rate is marginally higher than a
+// BloomFilter's.
+// see Chang, Feng & Li, "Approximate Caches for Packet Classification"
+// A PartitionedBloomFilter is not safe for concurrent use.
+type PartitionedBloomFilter[T any] struct {
+	keyHasher[T]
+	bs       *bitset.Bitset
+	slice    uint64
+	k        uint32
+	elements uint64
+	capacity uint64
+	fpRate   float64
+}
+
+// NewPartitioned creates a partitioned bloom filter described by cfg with
+// opts applied on top. The bit size is rounded up to a multiple of the hash
+// count.
+func NewPartitioned[T any](cfg Config, opts ...Option) (*PartitionedBloomFilter[T], error) {
+	p, enc, err := resolveFor[T](&cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.bits > maxBits-uint64(p.k) {
+		return nil, fmt.Errorf("%w: %d bits", ErrFilterTooLarge, p.bits)
+	}
+
+	slice := (p.bits + uint64(p.k) - 1) / uint64(p.k)
+	return &PartitionedBloomFilter[T]{
+		keyHasher: newKeyHasher(p.family, p.key, enc),
+		bs:        bitset.NewBitset(slice * uint64(p.k)),
+		slice:     slice,
+		k:         p.k,
+		capacity:  p.capacity,
+		fpRate:    p.fpRate,
+	}, nil
+}
+
+// probes returns the sequence of offsets within a slice for a key; the i-th
+// offset belongs to slice i.
+func (pf *PartitionedBloomFilter[T]) probes(data T) ihash.Probes {
+	h1, h2 := pf.sum(data)
+	return ihash.NewProbes(h1, h2, pf.slice)
+}
+
+// Insert adds an element to the filter.
+// If the element is already present (or appears to be due to hash collisions),
+// it will not be added again.
+func (pf *PartitionedBloomFilter[T]) Insert(data T) error {
+	probes := pf.probes(data)
+	if pf.containsProbes(probes) {
+		return nil
+	}
+
+	pf.elements++
+	for i := uint64(0); i < uint64(pf.k); i++ {
+		if err := pf.bs.Set(i*pf.slice + probes.Next()); err != nil {
+			return fmt.Errorf("inserting key: %w", err)
+		}
+	}
+	return nil
+}
+
+// Contains checks if an element might be in the filter.
+// Returns true if the element might be present (with possible false positives).
+// Returns false if the element is definitely not present.
+func (pf *PartitionedBloomFilter[T]) Contains(data T) bool {
+	return pf.containsProbes(pf.probes(data))
+}
+
+func (pf *PartitionedBloomFilter[T]) containsProbes(probes ihash.Probes) bool {
+	for i := uint64(0); i < uint64(pf.k); i++ {
+		set, _ := pf.bs.IsSet(i*pf.slice + probes.Next())
+		if !set {
+			return false
+		}
+	}
+	return true
+}
+
+// Size returns the total bit size of the filter.
+func (pf *PartitionedBloomFilter[T]) Size() uint64 {
+	return pf.bs.Size()
+}
+
+// SliceSize returns the bit size of each of the filter's HashCount slices.
+func (pf *PartitionedBloomFilter[T]) SliceSize() uint64 {
+	return pf.slice
+}
+
+// HashCount returns the number of hash functions, and slices, of the filter.
+func (pf *PartitionedBloomFilter[T]) HashCount() uint32 {
+	return pf.k
+}
+
+// Capacity returns the number of elements the filter was sized for.
+func (pf *PartitionedBloomFilter[T]) Capacity() uint64 {
+	return pf.capacity
+}
+
+// FalsePositiveRate returns the false-positive rate the filter was sized for,
+// expected once Capacity elements have been inserted.
+func (pf *PartitionedBloomFilter[T]) FalsePositiveRate() float64 {
+	return pf.fpRate
+}
+
+// sliceCounts returns the number of set bits in each slice.
+func (pf *PartitionedBloomFilter[T]) sliceCounts() []uint64 {
+	counts := make([]uint64, pf.k)
+	for i := range counts {
+		from := uint64(i) * pf.slice
+		counts[i], _ = pf.bs.CountRange(from, from+pf.slice)
+	}
+	return counts
+}
+
+// FillRatios returns the fraction of bits set in each slice.
+func (pf *PartitionedBloomFilter[T]) FillRatios() []float64 {
+	ratios := make([]float64, pf.k)
+	for i, x := range pf.sliceCounts() {
+		ratios[i] = float64(x) / float64(pf.slice)
+	}
+	return ratios
+}
+
+// Stats reports the filter's current fill and accuracy. Since every key sets
+// one bit per slice, the estimates are computed per slice: the false-positive
+// rate is the product of the slice fill ratios and the cardinality is the
+// mean of the single-hash estimates of the slices.
+func (pf *PartitionedBloomFilter[T]) Stats() Stats {
+	m := pf.Size()
+	s := Stats{
+		Bits:                       m,
+		HashCount:                  pf.k,
+		Inserted:                   pf.elements,
+		EstimatedFalsePositiveRate: 1,
+	}
+	for _, x := range pf.sliceCounts() {
+		s.BitsSet += x
+		s.EstimatedCardinality += estimateCardinality(pf.slice, 1, x)
+		s.EstimatedFalsePositiveRate *= float64(x) / float64(pf.slice)
+	}
+	s.FillRatio = float64(s.BitsSet) / float64(m)
+	s.EstimatedCardinality /= float64(pf.k)
+	return s
+}
+
+func (pf *PartitionedBloomFilter[T]) header() header {
+	return header{
+		layout:   LayoutPartitioned,
+		encoder:  pf.enc.ID(),
+		family:   pf.family.Name(),
+		key:      pf.key,
+		k:        pf.k,
+		bits:     pf.Size(),
+		count:    pf.elements,
+		capacity: pf.capacity,
+		fpRate:   pf.fpRate,
+	}
+}
+
+// WriteTo writes the filter to w in the serialized format, with the
+// partitioned layout.
+func (pf *PartitionedBloomFilter[T]) WriteTo(w io.Writer) (int64, error) {
+	return writeFilter(w, pf.header(), pf.bs.List())
+}
+
+// ReadFrom replaces the filter with one read from r in the serialized format.
+// The encoder is chosen as for BloomFilter.ReadFrom.
+func (pf *PartitionedBloomFilter[T]) ReadFrom(r io.Reader) (int64, error) {
+	d, n, err := readFilter(r, LayoutPartitioned, pf.enc)
+	if err != nil {
+		return n, err
+	}
+	if d.bits%uint64(d.k) != 0 {
+		return n, fmt.Errorf("%w: bit size %d is not a multiple of hash count %d", ErrInvalidFormat, d.bits, d.k)
+	}
+	bs, err := d.bitset()
+	if err != nil {
+		return n, err
+	}
+
+	*pf = PartitionedBloomFilter[T]{
+		keyHasher: d.keyHasher,
+		bs:        bs,
+		slice:     d.bits / uint64(d.k),
+		k:         d.k,
+		elements:  d.count,
+		capacity:  d.capacity,
+		fpRate:    d.fpRate,
+	}
+	return n, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (pf *PartitionedBloomFilter[T]) MarshalBinary() ([]byte, error) {
+	return marshal(64+len(pf.family.Name())+len(pf.bs.List()), pf.WriteTo)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. See ReadFrom.
+func (pf *PartitionedBloomFilter[T]) UnmarshalBinary(data []byte) error {
+	return unmarshal(data, pf.ReadFrom)
+}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNewPartitioned(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantSlice uint64
+		wantK     uint32
+		wantErr   error
+	}{
+		{"exact multiple", Config{Bits: 700, HashCount: 7}, 100, 7, nil},
+		{"rounded up", Config{Bits: 1000, HashCount: 7}, 143, 7, nil},
+		{"from estimates", Config{Capacity: 1000, FalsePositiveRate: 0.01}, 1370, 7, nil},
+		{"zero config", Config{}, 0, 0, ErrInvalidSize},
+		{"conflicting", Config{Bits: 100, FalsePositiveRate: 0.01}, 0, 0, ErrConflictingConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewPartitioned[int](tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewPartitioned() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if f.SliceSize() != tt.wantSlice || f.HashCount() != tt.wantK {
+				t.Errorf("SliceSize(), HashCount() = %d, %d, want %d, %d", f.SliceSize(), f.HashCount(), tt.wantSlice, tt.wantK)
+			}
+			if f.Size() != tt.wantSlice*uint64(tt.wantK) {
+				t.Errorf("Size() = %d, want %d", f.Size(), tt.wantSlice*uint64(tt.wantK))
+			}
+		})
+	}
+}
+
+func TestPartitionedBloomFilter_OneBitPerSlice(t *testing.T) {
+	f := Must(NewPartitioned[string](Config{Bits: 1 << 12, HashCount: 8}))
+	f.Insert("hello")
+
+	for i, fill := range f.FillRatios() {
+		if want := 1 / float64(f.SliceSize()); fill != want {
+			t.Errorf("FillRatios()[%d] = %g, want %g", i, fill, want)
+		}
+	}
+	if s := f.Stats(); s.BitsSet != 8 || s.Inserted != 1 {
+		t.Errorf("BitsSet, Inserted = %d, %d, want 8, 1", s.BitsSet, s.Inserted)
+	}
+}
+
+func TestPartitionedBloomFilter_NoFalseNegatives(t *testing.T) {
+	const n = 5000
+	f := Must(NewPartitioned[int](Config{Capacity: n, FalsePositiveRate: 0.01}))
+	for i := 0; i < n; i++ {
+		if err := f.Insert(i); err != nil {
+			t.Fatalf("Insert(%d) error = %v", i, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false, want true", i)
+		}
+	}
+
+	var fp int
+	for i := n; i < 11*n; i++ {
+		if f.Contains(i) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / (10 * n); rate > 0.015 {
+		t.Errorf("false-positive rate = %g, want about 0.01", rate)
+	}
+}
+
+func TestPartitionedBloomFilter_Stats(t *testing.T) {
+	const n = 5000
+	f := Must(NewPartitioned[int](Config{Capacity: n, FalsePositiveRate: 0.01}))
+	for i := 0; i < n; i++ {
+		f.Insert(i)
+	}
+
+	s := f.Stats()
+	want := 1.0
+	for _, fill := range f.FillRatios() {
+		if math.Abs(fill-0.5) > 0.05 {
+			t.Errorf("slice fill ratio = %g, want about 0.5 at capacity", fill)
+		}
+		want *= fill
+	}
+	if math.Abs(s.EstimatedFalsePositiveRate-want) > 1e-12 {
+		t.Errorf("EstimatedFalsePositiveRate = %g, want product of fills %g", s.EstimatedFalsePositiveRate, want)
+	}
+	if math.Abs(s.EstimatedCardinality-n)/n > 0.05 {
+		t.Errorf("EstimatedCardinality = %g, want within 5%% of %d", s.EstimatedCardinality, n)
+	}
+}
+
+func TestPartitionedBloomFilter_MarshalRoundTrip(t *testing.T) {
+	f := Must(NewPartitioned[string](Config{Bits: 1000, HashCount: 7}, WithSecretKey()))
+	for _, key := range []string{"a", "b", "c"} {
+		f.Insert(key)
+	}
+
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	var g PartitionedBloomFilter[string]
+	if err := g.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if g.Size() != f.Size() || g.SliceSize() != f.SliceSize() || g.Key() != f.Key() || g.Stats() != f.Stats() {
+		t.Errorf("round trip changed parameters: got %+v, want %+v", g.Stats(), f.Stats())
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if !g.Contains(key) {
+			t.Errorf("Contains(%q) = false after round trip, want true", key)
+		}
+	}
+
+	var classic BloomFilter[string]
+	if err := classic.UnmarshalBinary(data); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("BloomFilter.UnmarshalBinary(partitioned) error = %v, want %v", err, ErrInvalidFormat)
+	}
+}
+
+func TestNewFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		layout  Layout
+		wantErr error
+	}{
+		{"classic", LayoutClassic, nil},
+		{"partitioned", LayoutPartitioned, nil},
+		{"unknown", Layout(9), ErrConflictingConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter[string](Config{Bits: 1 << 10, Layout: tt.layout})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewFilter() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			f.Insert("key")
+
+			data, err := f.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error = %v", err)
+			}
+			g, err := UnmarshalFilter[string](data)
+			if err != nil {
+				t.Fatalf("UnmarshalFilter() error = %v", err)
+			}
+			if g.Stats() != f.Stats() || !g.Contains("key") {
+				t.Errorf("UnmarshalFilter() = %+v, want %+v", g.Stats(), f.Stats())
+			}
+			switch g.(type) {
+			case *BloomFilter[string]:
+				if tt.layout != LayoutClassic {
+					t.Errorf("UnmarshalFilter() returned %T for layout %d", g, tt.layout)
+				}
+			case *PartitionedBloomFilter[string]:
+				if tt.layout != LayoutPartitioned {
+					t.Errorf("UnmarshalFilter() returned %T for layout %d", g, tt.layout)
+				}
+			}
+		})
+	}
+
+	if _, err := UnmarshalFilter[string]([]byte("BLS")); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("UnmarshalFilter(short) error = %v, want %v", err, ErrInvalidFormat)
+	}
+}
@@ -0,0 +1,415 @@
+package core
+
+import (
+	"alex/bvs/internal/packed"
+	"cmp"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+const (
+	defaultRemainderBits = 8
+	maxRemainderBits     = 58
+
+	// quotientMaxLoad is the load factor the filter is sized for and that
+	// Insert refuses to exceed; runs grow quickly beyond it.
+	quotientMaxLoad = 0.75
+)
+
+// Slot metadata, stored in the low bits of each slot below the remainder.
+const (
+	// qfOccupied marks a slot whose index is the quotient of some element.
+	qfOccupied = 1 << iota
+	// qfContinuation marks an element that is not the first of its run.
+	qfContinuation
+	// qfShifted marks an element stored past its quotient's slot.
+	qfShifted
+
+	qfMetaBits = 3
+	qfMetaMask = 1<<qfMetaBits - 1
+)
+
+// QuotientFilter is an approximate multiset that supports deletion, resizing
+// and merging without access to the original keys. Each element is reduced
+// to a fingerprint of QuotientBits+RemainderBits bits; the quotient picks a
+// slot and only the remainder is stored, in sorted runs that are shifted
+// forward like linear probing when slots collide. Because the full
+// fingerprint can be recovered from the table, Resize can double the slots
+// by moving one remainder bit into the quotient, and two filters can be
+// merged in one pass over both.
+// The table is not circular: clusters near the end spill into a few padding
+// slots.
+// see Bender et al., "Don't Thrash: How to Cache Your Hash on Flash"
+// A QuotientFilter is not safe for concurrent use.
+type QuotientFilter[T any] struct {
+	keyHasher[T]
+	slots   *packed.Array
+	qbits   uint8
+	rbits   uint8
+	count   uint64
+	scratch []qfEntry
+}
+
+// qfEntry is an element's fingerprint split into quotient and remainder.
+type qfEntry struct {
+	q, r uint64
+}
+
+func compareEntries(a, b qfEntry) int {
+	if c := cmp.Compare(a.q, b.q); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.r, b.r)
+}
+
+// QuotientConfig describes a quotient filter.
+type QuotientConfig struct {
+	// Capacity sets the number of slots, a power of two at least
+	// Capacity/0.75.
+	Capacity uint64
+	// FalsePositiveRate is the target rate at Capacity elements, in (0, 1).
+	// It derives the remainder width when RemainderBits is zero.
+	FalsePositiveRate float64
+	// RemainderBits is the number of fingerprint bits stored per slot, in
+	// [1, 58]. Zero derives it from FalsePositiveRate, or selects 8.
+	RemainderBits uint8
+}
+
+// NewQuotient creates a quotient filter described by cfg. Only the hashing
+// options apply: WithEncoder, WithHashFamily, WithKey, WithSecretKey and
+// WithFixedKey.
+func NewQuotient[T any](cfg QuotientConfig, opts ...Option) (*QuotientFilter[T], error) {
+	if cfg.FalsePositiveRate != 0 && !(cfg.FalsePositiveRate > 0 && cfg.FalsePositiveRate < 1) {
+		return nil, fmt.Errorf("%w: %g", ErrInvalidFalsePositiveRate, cfg.FalsePositiveRate)
+	}
+	if cfg.Capacity == 0 {
+		return nil, ErrInvalidSize
+	}
+
+	r := uint64(cfg.RemainderBits)
+	switch {
+	case r != 0:
+	case cfg.FalsePositiveRate != 0:
+		// At load a a lookup matches a stored remainder with chance about a*2^-r.
+		r = uint64(max(math.Ceil(math.Log2(quotientMaxLoad/cfg.FalsePositiveRate)), 1))
+	default:
+		r = defaultRemainderBits
+	}
+	if r > maxRemainderBits {
+		return nil, fmt.Errorf("%w: remainder of %d bits exceeds %d", ErrInvalidWidth, r, maxRemainderBits)
+	}
+	q := uint64(max(bits.Len64(uint64(math.Ceil(float64(cfg.Capacity)/quotientMaxLoad))-1), 1))
+	if q+r > 64 || exceedsMaxBits(float64(uint64(1)<<q)*float64(r+qfMetaBits)) {
+		return nil, fmt.Errorf("%w: %d elements with %d-bit remainders", ErrFilterTooLarge, cfg.Capacity, r)
+	}
+
+	kh, err := keyHasherFor[T](optionsConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+	return &QuotientFilter[T]{
+		keyHasher: kh,
+		slots:     newQuotientSlots(uint8(q), uint8(r)),
+		qbits:     uint8(q),
+		rbits:     uint8(r),
+	}, nil
+}
+
+// newQuotientSlots allocates 2^q slots plus padding for clusters that run
+// past the last one.
+func newQuotientSlots(q, r uint8) *packed.Array {
+	padding := 64 + 8*uint64(q)
+	return packed.NewArray(uint64(1)<<q+padding, r+qfMetaBits)
+}
+
+// split returns the quotient and remainder of a key's fingerprint.
+func (qf *QuotientFilter[T]) split(data T) qfEntry {
+	h1, _ := qf.sum(data)
+	fp := h1 >> (64 - qf.qbits - qf.rbits)
+	return qfEntry{q: fp >> qf.rbits, r: fp & (1<<qf.rbits - 1)}
+}
+
+func (qf *QuotientFilter[T]) empty(i uint64) bool {
+	return qf.slots.Get(i)&qfMetaMask == 0
+}
+
+// region returns the start of the maximal run of non-empty slots around
+// slot q, or q itself if it is empty.
+func (qf *QuotientFilter[T]) region(q uint64) uint64 {
+	start := q
+	for start > 0 && !qf.empty(start) && !qf.empty(start-1) {
+		start--
+	}
+	return start
+}
+
+// decode appends the entries stored from start, which must begin a region,
+// up to the next empty slot, and returns the end of the region.
+func (qf *QuotientFilter[T]) decode(start uint64, dst []qfEntry) ([]qfEntry, uint64) {
+	next := start
+	var q uint64
+	i := start
+	for ; i < qf.slots.Len() && !qf.empty(i); i++ {
+		v := qf.slots.Get(i)
+		if v&qfContinuation == 0 {
+			// Runs are stored in the order of their quotients' occupied bits.
+			for qf.slots.Get(next)&qfOccupied == 0 {
+				next++
+			}
+			q, next = next, next+1
+		}
+		dst = append(dst, qfEntry{q: q, r: v >> qfMetaBits})
+	}
+	return dst, i
+}
+
+// encode lays entries, sorted by quotient and remainder, out from start,
+// clearing the slots of the old region up to end. It returns ErrFilterFull,
+// changing nothing, if they would run past the last slot.
+func (qf *QuotientFilter[T]) encode(start, end uint64, entries []qfEntry) error {
+	pos := start
+	for i, e := range entries {
+		if i == 0 || e.q != entries[i-1].q {
+			pos = max(pos, e.q)
+		}
+		pos++
+	}
+	if pos > qf.slots.Len() {
+		return fmt.Errorf("%w: cluster runs past the last slot", ErrFilterFull)
+	}
+
+	for i := start; i < max(end, pos); i++ {
+		qf.slots.Set(i, 0)
+	}
+	pos = start
+	for i, e := range entries {
+		var meta uint64
+		if i == 0 || e.q != entries[i-1].q {
+			pos = max(pos, e.q)
+		} else {
+			meta |= qfContinuation
+		}
+		if pos != e.q {
+			meta |= qfShifted
+		}
+		qf.slots.Set(pos, e.r<<qfMetaBits|meta)
+		pos++
+	}
+	for _, e := range entries {
+		qf.slots.Set(e.q, qf.slots.Get(e.q)|qfOccupied)
+	}
+	return nil
+}
+
+// Insert adds one occurrence of an element. Like CountingBloomFilter,
+// repeated inserts are stored so that each can be matched by a Delete.
+// It returns ErrFilterFull, leaving the filter unchanged, once the load
+// factor reaches 0.75; Resize makes room.
+func (qf *QuotientFilter[T]) Insert(data T) error {
+	if qf.count >= qf.Capacity() {
+		return fmt.Errorf("%w: %d elements", ErrFilterFull, qf.count)
+	}
+
+	e := qf.split(data)
+	start := qf.region(e.q)
+	entries, end := qf.decode(start, qf.scratch[:0])
+	i, _ := slices.BinarySearchFunc(entries, e, compareEntries)
+	entries = slices.Insert(entries, i, e)
+	qf.scratch = entries
+
+	if err := qf.encode(start, end, entries); err != nil {
+		return err
+	}
+	qf.count++
+	return nil
+}
+
+// Contains checks if an element might be in the filter.
+// Returns true if the element might be present (with possible false positives).
+// Returns false if the element is definitely not present.
+func (qf *QuotientFilter[T]) Contains(data T) bool {
+	e := qf.split(data)
+	if qf.slots.Get(e.q)&qfOccupied == 0 {
+		return false
+	}
+
+	entries, _ := qf.decode(qf.region(e.q), qf.scratch[:0])
+	qf.scratch = entries
+	_, found := slices.BinarySearchFunc(entries, e, compareEntries)
+	return found
+}
+
+// Delete removes one occurrence of an element. It returns ErrNotPresent if
+// the element is definitely not in the filter. Deleting an element that was
+// never inserted but is reported present by a false positive removes
+// another element's fingerprint.
+func (qf *QuotientFilter[T]) Delete(data T) error {
+	e := qf.split(data)
+	if qf.slots.Get(e.q)&qfOccupied == 0 {
+		return ErrNotPresent
+	}
+
+	start := qf.region(e.q)
+	entries, end := qf.decode(start, qf.scratch[:0])
+	qf.scratch = entries
+	i, found := slices.BinarySearchFunc(entries, e, compareEntries)
+	if !found {
+		return ErrNotPresent
+	}
+	entries = slices.Delete(entries, i, i+1)
+
+	if err := qf.encode(start, end, entries); err != nil {
+		return err
+	}
+	qf.count--
+	return nil
+}
+
+// entries returns every stored entry in fingerprint order.
+func (qf *QuotientFilter[T]) entries() []qfEntry {
+	all := make([]qfEntry, 0, qf.count)
+	for i := uint64(0); i < qf.slots.Len(); i++ {
+		if !qf.empty(i) {
+			all, i = qf.decode(i, all)
+		}
+	}
+	return all
+}
+
+// rebuild lays fingerprints of qf's width out in a fresh table with q
+// quotient bits, replacing qf's table. The fingerprints must be sorted.
+func (qf *QuotientFilter[T]) rebuild(q uint8, fingerprints []uint64) error {
+	r := qf.qbits + qf.rbits - q
+	entries := make([]qfEntry, len(fingerprints))
+	for i, fp := range fingerprints {
+		entries[i] = qfEntry{q: fp >> r, r: fp & (1<<r - 1)}
+	}
+
+	next := &QuotientFilter[T]{
+		keyHasher: qf.keyHasher,
+		slots:     newQuotientSlots(q, r),
+		qbits:     q,
+		rbits:     r,
+		count:     uint64(len(entries)),
+	}
+	if err := next.encode(0, 0, entries); err != nil {
+		return err
+	}
+	*qf = *next
+	return nil
+}
+
+// fingerprints returns every stored fingerprint in sorted order.
+func (qf *QuotientFilter[T]) fingerprints() []uint64 {
+	entries := qf.entries()
+	fps := make([]uint64, len(entries))
+	for i, e := range entries {
+		fps[i] = e.q<<qf.rbits | e.r
+	}
+	return fps
+}
+
+// Resize doubles the number of slots by moving one bit of every
+// fingerprint from the remainder into the quotient, in one pass over the
+// table. The false-positive rate at a given load doubles. It returns
+// ErrFilterTooLarge once a single remainder bit is left.
+func (qf *QuotientFilter[T]) Resize() error {
+	if qf.rbits <= 1 {
+		return fmt.Errorf("%w: no remainder bits left to move", ErrFilterTooLarge)
+	}
+	return qf.rebuild(qf.qbits+1, qf.fingerprints())
+}
+
+// Compatible reports whether other can be merged into qf: both must have the
+// same fingerprint width, hash family, key and key encoder.
+func (qf *QuotientFilter[T]) Compatible(other *QuotientFilter[T]) error {
+	if qf.qbits+qf.rbits != other.qbits+other.rbits {
+		return &IncompatibleError{"fingerprint bits", qf.qbits + qf.rbits, other.qbits + other.rbits}
+	}
+	return qf.keyHasher.compatible(&other.keyHasher)
+}
+
+// MergeWith adds every element of other to qf, in time linear in the size
+// of both tables. qf takes the larger of the two sizes, doubled further by
+// moving remainder bits while the merged load would exceed 0.75.
+func (qf *QuotientFilter[T]) MergeWith(other *QuotientFilter[T]) error {
+	if err := qf.Compatible(other); err != nil {
+		return err
+	}
+
+	a, b := qf.fingerprints(), other.fingerprints()
+	merged := make([]uint64, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0] <= b[0] {
+			merged, a = append(merged, a[0]), a[1:]
+		} else {
+			merged, b = append(merged, b[0]), b[1:]
+		}
+	}
+	merged = append(append(merged, a...), b...)
+
+	q := max(qf.qbits, other.qbits)
+	width := qf.qbits + qf.rbits
+	for float64(len(merged)) > quotientMaxLoad*float64(uint64(1)<<q) {
+		if q+1 >= width {
+			return fmt.Errorf("%w: %d elements", ErrFilterFull, len(merged))
+		}
+		q++
+	}
+	return qf.rebuild(q, merged)
+}
+
+// Merge returns a new filter holding the elements of both qf and other.
+// See MergeWith.
+func (qf *QuotientFilter[T]) Merge(other *QuotientFilter[T]) (*QuotientFilter[T], error) {
+	result := &QuotientFilter[T]{
+		keyHasher: newKeyHasher(qf.family, qf.key, qf.enc),
+		slots:     qf.slots,
+		qbits:     qf.qbits,
+		rbits:     qf.rbits,
+		count:     qf.count,
+	}
+	if err := result.MergeWith(other); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Len returns the number of elements inserted and not yet deleted.
+func (qf *QuotientFilter[T]) Len() uint64 {
+	return qf.count
+}
+
+// Capacity returns the number of elements Insert accepts before Resize is
+// needed: 0.75 of the slots.
+func (qf *QuotientFilter[T]) Capacity() uint64 {
+	return uint64(quotientMaxLoad * float64(uint64(1)<<qf.qbits))
+}
+
+// LoadFactor returns the number of elements per quotient slot.
+func (qf *QuotientFilter[T]) LoadFactor() float64 {
+	return float64(qf.count) / float64(uint64(1)<<qf.qbits)
+}
+
+// QuotientBits returns the number of fingerprint bits that select a slot.
+func (qf *QuotientFilter[T]) QuotientBits() uint8 {
+	return qf.qbits
+}
+
+// RemainderBits returns the number of fingerprint bits stored per slot.
+func (qf *QuotientFilter[T]) RemainderBits() uint8 {
+	return qf.rbits
+}
+
+// Size returns the total bit size of the table, padding included.
+func (qf *QuotientFilter[T]) Size() uint64 {
+	return qf.slots.Len() * uint64(qf.slots.Width())
+}
+
+// FalsePositiveRate returns the chance that a key not in the filter matches
+// a stored fingerprint at the current load, 1 - e^(-load/2^r).
+func (qf *QuotientFilter[T]) FalsePositiveRate() float64 {
+	return -math.Expm1(-qf.LoadFactor() * math.Ldexp(1, -int(qf.rbits)))
+}
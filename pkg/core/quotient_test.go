@@ -0,0 +1,220 @@
+package core
+
+import (
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestNewQuotient(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     QuotientConfig
+		wantErr error
+		wantQ   uint8
+		wantR   uint8
+	}{
+		{"default remainder", QuotientConfig{Capacity: 1000}, nil, 11, 8},
+		{"rate sets remainder", QuotientConfig{Capacity: 1000, FalsePositiveRate: 0.001}, nil, 11, 10},
+		{"custom remainder", QuotientConfig{Capacity: 100, RemainderBits: 5}, nil, 8, 5},
+		{"single element", QuotientConfig{Capacity: 1}, nil, 1, 8},
+		{"no capacity", QuotientConfig{}, ErrInvalidSize, 0, 0},
+		{"remainder too wide", QuotientConfig{Capacity: 10, RemainderBits: 59}, ErrInvalidWidth, 0, 0},
+		{"fingerprint too wide", QuotientConfig{Capacity: 1 << 40, RemainderBits: 30}, ErrFilterTooLarge, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewQuotient[string](tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewQuotient() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if f.QuotientBits() != tt.wantQ || f.RemainderBits() != tt.wantR {
+				t.Errorf("QuotientBits(), RemainderBits() = %d, %d, want %d, %d", f.QuotientBits(), f.RemainderBits(), tt.wantQ, tt.wantR)
+			}
+			if f.Capacity() < tt.cfg.Capacity {
+				t.Errorf("Capacity() = %d, want at least %d", f.Capacity(), tt.cfg.Capacity)
+			}
+		})
+	}
+}
+
+// checkQuotient compares the decoded table with the multiset of entries the
+// filter should hold.
+func checkQuotient(t *testing.T, f *QuotientFilter[int], model map[qfEntry]int) {
+	t.Helper()
+	var want []qfEntry
+	for e, n := range model {
+		for range n {
+			want = append(want, e)
+		}
+	}
+	slices.SortFunc(want, compareEntries)
+	if got := f.entries(); !slices.Equal(got, want) {
+		t.Fatalf("table holds %d entries, want %d matching the model", len(got), len(want))
+	}
+	if f.Len() != uint64(len(want)) {
+		t.Fatalf("Len() = %d, want %d", f.Len(), len(want))
+	}
+}
+
+func TestQuotientFilter_RandomOps(t *testing.T) {
+	// A small table at high load keeps clusters long and makes them merge.
+	f := Must(NewQuotient[int](QuotientConfig{Capacity: 48, RemainderBits: 4}))
+	model := make(map[qfEntry]int)
+	r := rand.New(rand.NewPCG(1, 2))
+
+	for i := 0; i < 5000; i++ {
+		key := r.IntN(200)
+		e := f.split(key)
+		if r.IntN(3) > 0 && f.Len() < f.Capacity() {
+			if err := f.Insert(key); err != nil {
+				t.Fatalf("Insert(%d) error = %v", key, err)
+			}
+			model[e]++
+		} else {
+			err := f.Delete(key)
+			if model[e] == 0 {
+				if !errors.Is(err, ErrNotPresent) {
+					t.Fatalf("Delete(%d) error = %v, want %v", key, err, ErrNotPresent)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("Delete(%d) error = %v", key, err)
+			}
+			if model[e]--; model[e] == 0 {
+				delete(model, e)
+			}
+		}
+		checkQuotient(t, f, model)
+		if got := f.Contains(key); got != (model[e] > 0) {
+			t.Fatalf("Contains(%d) = %v, want %v", key, got, model[e] > 0)
+		}
+	}
+}
+
+func TestQuotientFilter_InsertDelete(t *testing.T) {
+	const n = 10000
+	f := Must(NewQuotient[int](QuotientConfig{Capacity: n, FalsePositiveRate: 0.001}))
+	for i := 0; i < n; i++ {
+		if err := f.Insert(i); err != nil {
+			t.Fatalf("Insert(%d) error = %v", i, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false, want true", i)
+		}
+	}
+
+	var fp int
+	for i := n; i < 11*n; i++ {
+		if f.Contains(i) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / (10 * n); rate > 0.001 {
+		t.Errorf("false-positive rate = %g, want at most 0.001", rate)
+	}
+
+	for i := 0; i < n; i += 2 {
+		if err := f.Delete(i); err != nil {
+			t.Fatalf("Delete(%d) error = %v", i, err)
+		}
+	}
+	for i := 1; i < n; i += 2 {
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false for a kept element, want true", i)
+		}
+	}
+	if f.Len() != n/2 {
+		t.Errorf("Len() = %d, want %d", f.Len(), n/2)
+	}
+}
+
+func TestQuotientFilter_Full(t *testing.T) {
+	f := Must(NewQuotient[int](QuotientConfig{Capacity: 12}))
+	for i := uint64(0); i < f.Capacity(); i++ {
+		if err := f.Insert(int(i)); err != nil {
+			t.Fatalf("Insert(%d) error = %v", i, err)
+		}
+	}
+	if err := f.Insert(-1); !errors.Is(err, ErrFilterFull) {
+		t.Errorf("Insert() at capacity error = %v, want %v", err, ErrFilterFull)
+	}
+}
+
+func TestQuotientFilter_Resize(t *testing.T) {
+	f := Must(NewQuotient[int](QuotientConfig{Capacity: 100, RemainderBits: 10}))
+	for i := 0; i < 100; i++ {
+		f.Insert(i)
+	}
+	f.Insert(7)
+	before := f.fingerprints()
+
+	if err := f.Resize(); err != nil {
+		t.Fatalf("Resize() error = %v", err)
+	}
+	if f.QuotientBits() != 9 || f.RemainderBits() != 9 {
+		t.Errorf("QuotientBits(), RemainderBits() = %d, %d, want 9, 9", f.QuotientBits(), f.RemainderBits())
+	}
+	if !slices.Equal(f.fingerprints(), before) {
+		t.Error("Resize() changed the stored fingerprints")
+	}
+	for i := 0; i < 100; i++ {
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false after Resize, want true", i)
+		}
+	}
+	for i := 100; i < 200; i++ {
+		if err := f.Insert(i); err != nil {
+			t.Fatalf("Insert(%d) after Resize error = %v", i, err)
+		}
+	}
+
+	small := Must(NewQuotient[int](QuotientConfig{Capacity: 4, RemainderBits: 1}))
+	if err := small.Resize(); !errors.Is(err, ErrFilterTooLarge) {
+		t.Errorf("Resize() with one remainder bit error = %v, want %v", err, ErrFilterTooLarge)
+	}
+}
+
+func TestQuotientFilter_Merge(t *testing.T) {
+	a := Must(NewQuotient[int](QuotientConfig{Capacity: 500, RemainderBits: 10}))
+	b := Must(NewQuotient[int](QuotientConfig{Capacity: 1000, RemainderBits: 9}, WithKey(a.Key())))
+	for i := 0; i < 500; i++ {
+		a.Insert(i)
+	}
+	for i := 400; i < 1200; i++ {
+		b.Insert(i)
+	}
+
+	m, err := a.Merge(b)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if m.Len() != 1300 {
+		t.Errorf("Len() = %d, want 1300", m.Len())
+	}
+	if m.QuotientBits()+m.RemainderBits() != 20 || m.LoadFactor() > quotientMaxLoad {
+		t.Errorf("merged filter has %d+%d bits at load %g", m.QuotientBits(), m.RemainderBits(), m.LoadFactor())
+	}
+	for i := 0; i < 1200; i++ {
+		if !m.Contains(i) {
+			t.Fatalf("Contains(%d) = false after Merge, want true", i)
+		}
+	}
+	if a.Len() != 500 || !a.Contains(0) {
+		t.Error("Merge() modified its receiver")
+	}
+
+	c := Must(NewQuotient[int](QuotientConfig{Capacity: 500, RemainderBits: 8}, WithKey(a.Key())))
+	var ie *IncompatibleError
+	if err := a.MergeWith(c); !errors.As(err, &ie) || ie.Field != "fingerprint bits" {
+		t.Errorf("MergeWith() error = %v, want IncompatibleError on fingerprint bits", err)
+	}
+}
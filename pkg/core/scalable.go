@@ -0,0 +1,188 @@
+package core
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	defaultGrowthFactor    = 2
+	defaultTighteningRatio = 0.85
+)
+
+// ScalableBloomFilter is a bloom filter that grows past its initial capacity
+// by chaining stages. Each stage holds GrowthFactor times more elements than
+// the previous one at a false-positive rate TighteningRatio times lower, so
+// the rates form a geometric series whose sum stays under the configured
+// ceiling however many stages are added. A new stage is started once the
+// current one holds its capacity, at which point its fill ratio is about 1/2.
+// see Almeida et al., "Scalable Bloom Filters"
+// A ScalableBloomFilter is not safe for concurrent use.
+type ScalableBloomFilter[T any] struct {
+	keyHasher[T]
+	stages   []*BloomFilter[T]
+	base     Config
+	growth   float64
+	ratio    float64
+	fpRate   float64
+	elements uint64
+}
+
+// ScalableConfig describes a scalable bloom filter. The size and hash count
+// of each stage are derived from it.
+type ScalableConfig struct {
+	// Capacity is the number of elements the first stage holds.
+	Capacity uint64
+	// FalsePositiveRate is the ceiling on the compound false-positive rate of
+	// all stages, in (0, 1).
+	FalsePositiveRate float64
+	// GrowthFactor is how much larger each stage is than the one before, at
+	// least 1. Zero selects 2.
+	GrowthFactor float64
+	// TighteningRatio scales the false-positive rate of each successive
+	// stage, in (0, 1). Zero selects 0.85.
+	TighteningRatio float64
+}
+
+// NewScalable creates a scalable bloom filter described by cfg. Only the
+// hashing options apply: WithEncoder, WithHashFamily, WithKey,
+// WithSecretKey and WithFixedKey.
+func NewScalable[T any](cfg ScalableConfig, opts ...Option) (*ScalableBloomFilter[T], error) {
+	if !(cfg.FalsePositiveRate > 0 && cfg.FalsePositiveRate < 1) {
+		return nil, fmt.Errorf("%w: %g", ErrInvalidFalsePositiveRate, cfg.FalsePositiveRate)
+	}
+
+	growth, ratio := cfg.GrowthFactor, cfg.TighteningRatio
+	if growth == 0 {
+		growth = defaultGrowthFactor
+	}
+	if ratio == 0 {
+		ratio = defaultTighteningRatio
+	}
+	if !(growth >= 1) || math.IsInf(growth, 1) {
+		return nil, fmt.Errorf("%w: growth factor %g, want at least 1", ErrInvalidScaling, growth)
+	}
+	if !(ratio > 0 && ratio < 1) {
+		return nil, fmt.Errorf("%w: tightening ratio %g, want in (0, 1)", ErrInvalidScaling, ratio)
+	}
+
+	base := optionsConfig(opts)
+	base.Capacity = cfg.Capacity
+
+	// Stage i gets rate P(1-r)r^i, so the rates sum to at most P.
+	first := base
+	first.FalsePositiveRate = cfg.FalsePositiveRate * (1 - ratio)
+	p, enc, err := resolveFor[T](&first, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Later stages must hash like the first, so pin the key it resolved.
+	base.Key, base.SecretKey, base.FixedKey = &p.key, false, false
+	sf := &ScalableBloomFilter[T]{
+		keyHasher: newKeyHasher(p.family, p.key, enc),
+		stages:    []*BloomFilter[T]{newBloomFilter(p, enc)},
+		base:      base,
+		growth:    growth,
+		ratio:     ratio,
+		fpRate:    cfg.FalsePositiveRate,
+	}
+	return sf, nil
+}
+
+// addStage appends a stage sized for the next step of the series.
+func (sf *ScalableBloomFilter[T]) addStage() error {
+	i := float64(len(sf.stages))
+	cfg := sf.base
+	cfg.Capacity = uint64(math.Ceil(float64(sf.base.Capacity) * math.Pow(sf.growth, i)))
+	cfg.FalsePositiveRate = sf.fpRate * (1 - sf.ratio) * math.Pow(sf.ratio, i)
+
+	p, err := cfg.resolve()
+	if err != nil {
+		return fmt.Errorf("adding stage %d: %w", len(sf.stages), err)
+	}
+	sf.stages = append(sf.stages, newBloomFilter(p, sf.enc))
+	return nil
+}
+
+// Insert adds an element to the filter, starting a new stage first if the
+// current one is at capacity. Elements that already appear present are not
+// added again.
+func (sf *ScalableBloomFilter[T]) Insert(data T) error {
+	h1, h2 := sf.sum(data)
+	if sf.containsSum(h1, h2) {
+		return nil
+	}
+
+	last := sf.stages[len(sf.stages)-1]
+	if last.elements >= last.capacity {
+		if err := sf.addStage(); err != nil {
+			return err
+		}
+		last = sf.stages[len(sf.stages)-1]
+	}
+	sf.elements++
+	return last.insertSum(h1, h2)
+}
+
+// Contains checks if an element might be in any stage of the filter.
+// Returns true if the element might be present (with possible false positives).
+// Returns false if the element is definitely not present.
+func (sf *ScalableBloomFilter[T]) Contains(data T) bool {
+	return sf.containsSum(sf.sum(data))
+}
+
+func (sf *ScalableBloomFilter[T]) containsSum(h1, h2 uint64) bool {
+	for _, stage := range sf.stages {
+		if stage.containsSum(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stages returns the number of stages.
+func (sf *ScalableBloomFilter[T]) Stages() int {
+	return len(sf.stages)
+}
+
+// Len returns the number of elements inserted.
+func (sf *ScalableBloomFilter[T]) Len() uint64 {
+	return sf.elements
+}
+
+// Size returns the total bit size of all stages.
+func (sf *ScalableBloomFilter[T]) Size() uint64 {
+	var size uint64
+	for _, stage := range sf.stages {
+		size += stage.Size()
+	}
+	return size
+}
+
+// Capacity returns the number of elements the current stages hold before
+// another one is added.
+func (sf *ScalableBloomFilter[T]) Capacity() uint64 {
+	var capacity uint64
+	for _, stage := range sf.stages {
+		capacity += stage.capacity
+	}
+	return capacity
+}
+
+// FalsePositiveRate returns the configured ceiling on the compound
+// false-positive rate.
+func (sf *ScalableBloomFilter[T]) FalsePositiveRate() float64 {
+	return sf.fpRate
+}
+
+// EstimatedFalsePositiveRate returns the compound false-positive rate of the
+// current stages, 1 - Π(1 - p_i), where p_i is each stage's rate at its
+// current fill.
+func (sf *ScalableBloomFilter[T]) EstimatedFalsePositiveRate() float64 {
+	pass := 1.0
+	for _, stage := range sf.stages {
+		pass *= 1 - stage.Stats().EstimatedFalsePositiveRate
+	}
+	return 1 - pass
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScalableBloomFilter_New(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ScalableConfig
+		wantErr error
+	}{
+		{"defaults", ScalableConfig{Capacity: 100, FalsePositiveRate: 0.01}, nil},
+		{"custom scaling", ScalableConfig{Capacity: 100, FalsePositiveRate: 0.01, GrowthFactor: 4, TighteningRatio: 0.5}, nil},
+		{"no growth", ScalableConfig{Capacity: 100, FalsePositiveRate: 0.01, GrowthFactor: 1}, nil},
+		{"missing capacity", ScalableConfig{FalsePositiveRate: 0.01}, ErrInvalidCapacity},
+		{"missing rate", ScalableConfig{Capacity: 100}, ErrInvalidFalsePositiveRate},
+		{"rate too high", ScalableConfig{Capacity: 100, FalsePositiveRate: 1.5}, ErrInvalidFalsePositiveRate},
+		{"shrinking", ScalableConfig{Capacity: 100, FalsePositiveRate: 0.01, GrowthFactor: 0.5}, ErrInvalidScaling},
+		{"ratio of one", ScalableConfig{Capacity: 100, FalsePositiveRate: 0.01, TighteningRatio: 1}, ErrInvalidScaling},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewScalable[int](tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewScalable() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && f.Stages() != 1 {
+				t.Errorf("Stages() = %d, want 1", f.Stages())
+			}
+		})
+	}
+}
+
+func TestScalableBloomFilter_Grows(t *testing.T) {
+	const ceiling = 0.01
+	f := Must(NewScalable[int](ScalableConfig{Capacity: 100, FalsePositiveRate: ceiling}, WithSecretKey()))
+
+	const n = 10000
+	for i := 0; i < n; i++ {
+		if err := f.Insert(i); err != nil {
+			t.Fatalf("Insert(%d) error = %v", i, err)
+		}
+	}
+
+	// 100 + 200 + ... + 6400 = 12700 elements fit in 7 stages.
+	if f.Stages() != 7 {
+		t.Errorf("Stages() = %d, want 7", f.Stages())
+	}
+	if f.Capacity() < n {
+		t.Errorf("Capacity() = %d, want at least %d", f.Capacity(), n)
+	}
+	for i := 0; i < n; i++ {
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false after insert, want true", i)
+		}
+	}
+
+	if got := f.EstimatedFalsePositiveRate(); got > ceiling {
+		t.Errorf("EstimatedFalsePositiveRate() = %g, want at most %g", got, ceiling)
+	}
+	falsePositives := 0
+	const probes = 50000
+	for i := n; i < n+probes; i++ {
+		if f.Contains(i) {
+			falsePositives++
+		}
+	}
+	if got := float64(falsePositives) / probes; got > ceiling {
+		t.Errorf("false-positive rate = %g, want at most %g", got, ceiling)
+	}
+}
+
+func TestScalableBloomFilter_Duplicates(t *testing.T) {
+	f := Must(NewScalable[string](ScalableConfig{Capacity: 10, FalsePositiveRate: 0.01}))
+	for i := 0; i < 100; i++ {
+		f.Insert("same")
+	}
+	if f.Len() != 1 || f.Stages() != 1 {
+		t.Errorf("Len(), Stages() = %d, %d, want 1, 1", f.Len(), f.Stages())
+	}
+}
+
+func BenchmarkScalableBloomFilter_Insert(b *testing.B) {
+	f := Must(NewScalable[int](ScalableConfig{Capacity: 1000, FalsePositiveRate: 0.01}))
+	for i := 0; i < b.N; i++ {
+		f.Insert(i)
+	}
+}
@@ -0,0 +1,90 @@
+package core
+
+import "math"
+
+// Estimate is an approximate quantity with an error bound of about one
+// standard deviation. Cardinalities are +Inf once a filter is saturated.
+type Estimate struct {
+	Value float64
+	Error float64
+}
+
+// similarity holds the cardinality estimates of two filters and their union.
+type similarity struct {
+	a, b, union Estimate
+}
+
+func (bf *BloomFilter[T]) similarity(other *BloomFilter[T]) (similarity, error) {
+	if err := bf.Compatible(other); err != nil {
+		return similarity{}, err
+	}
+	xOr, err := bf.bs.OrCount(other.bs)
+	if err != nil {
+		return similarity{}, err
+	}
+
+	m, k := bf.Size(), bf.k
+	estimate := func(x uint64) Estimate {
+		return Estimate{estimateCardinality(m, k, x), cardinalityError(m, k, x)}
+	}
+	return similarity{
+		a:     estimate(bf.bs.Count()),
+		b:     estimate(other.bs.Count()),
+		union: estimate(xOr),
+	}, nil
+}
+
+// EstimateUnion estimates the number of distinct elements in either filter
+// from the bits set in their OR.
+func (bf *BloomFilter[T]) EstimateUnion(other *BloomFilter[T]) (Estimate, error) {
+	s, err := bf.similarity(other)
+	return s.union, err
+}
+
+// EstimateIntersection estimates the number of elements in both filters as
+// |A| + |B| - |A ∪ B|. Since the OR of two bitsets sets |A| + |B| - |A AND B|
+// bits, this equals the estimate derived from the AND, without its bias
+// towards shared false-positive bits. The error bound adds the three
+// estimates' errors in quadrature.
+func (bf *BloomFilter[T]) EstimateIntersection(other *BloomFilter[T]) (Estimate, error) {
+	s, err := bf.similarity(other)
+	if err != nil {
+		return Estimate{}, err
+	}
+	return s.intersection(), nil
+}
+
+func (s similarity) intersection() Estimate {
+	return Estimate{
+		Value: max(s.a.Value+s.b.Value-s.union.Value, 0),
+		Error: math.Sqrt(s.a.Error*s.a.Error + s.b.Error*s.b.Error + s.union.Error*s.union.Error),
+	}
+}
+
+// Jaccard estimates the Jaccard similarity |A ∩ B| / |A ∪ B| of the two
+// filters' sets, propagating the relative errors of both estimates. Two
+// empty filters have similarity 1; a saturated union gives NaN.
+func (bf *BloomFilter[T]) Jaccard(other *BloomFilter[T]) (Estimate, error) {
+	s, err := bf.similarity(other)
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	inter, union := s.intersection(), s.union
+	switch {
+	case math.IsInf(union.Value, 1):
+		return Estimate{Value: math.NaN(), Error: math.Inf(1)}, nil
+	case union.Value == 0:
+		return Estimate{Value: 1}, nil
+	case inter.Value == 0:
+		return Estimate{Value: 0, Error: inter.Error / union.Value}, nil
+	}
+
+	j := min(inter.Value/union.Value, 1)
+	relInter := inter.Error / inter.Value
+	relUnion := union.Error / union.Value
+	return Estimate{
+		Value: j,
+		Error: j * math.Sqrt(relInter*relInter+relUnion*relUnion),
+	}, nil
+}
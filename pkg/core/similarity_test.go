@@ -0,0 +1,95 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestBloomFilter_SimilarityEstimates(t *testing.T) {
+	tests := []struct {
+		name                   string
+		aFrom, aTo, bFrom, bTo int
+		wantUnion, wantInter   float64
+		wantJaccard            float64
+	}{
+		{"half overlap", 0, 1000, 500, 1500, 1500, 500, 1.0 / 3},
+		{"identical", 0, 1000, 0, 1000, 1000, 1000, 1},
+		{"disjoint", 0, 800, 800, 1600, 1600, 0, 0},
+		{"subset", 0, 1000, 200, 400, 1000, 200, 0.2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Must(New[int](Config{Capacity: 4000, FalsePositiveRate: 0.01, FixedKey: true}))
+			b := Must(New[int](Config{Capacity: 4000, FalsePositiveRate: 0.01, FixedKey: true}))
+			for i := tt.aFrom; i < tt.aTo; i++ {
+				a.Insert(i)
+			}
+			for i := tt.bFrom; i < tt.bTo; i++ {
+				b.Insert(i)
+			}
+
+			check := func(name string, got Estimate, err error, want, tolerance float64) {
+				t.Helper()
+				if err != nil {
+					t.Fatalf("%s() error = %v", name, err)
+				}
+				if got.Error < 0 || math.IsNaN(got.Error) {
+					t.Errorf("%s() error bound = %g, want non-negative", name, got.Error)
+				}
+				if math.Abs(got.Value-want) > tolerance+3*got.Error {
+					t.Errorf("%s() = %g ± %g, want %g", name, got.Value, got.Error, want)
+				}
+			}
+
+			union, err := a.EstimateUnion(b)
+			check("EstimateUnion", union, err, tt.wantUnion, 0.02*tt.wantUnion)
+			inter, err := a.EstimateIntersection(b)
+			check("EstimateIntersection", inter, err, tt.wantInter, 0.02*tt.wantUnion)
+			jaccard, err := a.Jaccard(b)
+			check("Jaccard", jaccard, err, tt.wantJaccard, 0.03)
+		})
+	}
+}
+
+func TestBloomFilter_SimilarityEdgeCases(t *testing.T) {
+	t.Run("empty filters", func(t *testing.T) {
+		a, b := NewBloomFilter[int](1024, WithFixedKey()), NewBloomFilter[int](1024, WithFixedKey())
+		j, err := a.Jaccard(b)
+		if err != nil || j.Value != 1 {
+			t.Errorf("Jaccard() = %+v, %v, want 1", j, err)
+		}
+		u, err := a.EstimateUnion(b)
+		if err != nil || u.Value != 0 || u.Error != 0 {
+			t.Errorf("EstimateUnion() = %+v, %v, want 0 ± 0", u, err)
+		}
+	})
+
+	t.Run("saturated filters", func(t *testing.T) {
+		a, b := NewBloomFilter[int](16, WithFixedKey()), NewBloomFilter[int](16, WithFixedKey())
+		for i := 0; i < 100; i++ {
+			a.Insert(i)
+		}
+		u, _ := a.EstimateUnion(b)
+		if !math.IsInf(u.Value, 1) {
+			t.Errorf("EstimateUnion() = %+v, want +Inf", u)
+		}
+		if j, _ := a.Jaccard(b); !math.IsNaN(j.Value) {
+			t.Errorf("Jaccard() = %+v, want NaN", j)
+		}
+	})
+
+	t.Run("incompatible filters", func(t *testing.T) {
+		a, b := NewBloomFilter[int](1024), NewBloomFilter[int](2048)
+		if _, err := a.EstimateUnion(b); !errors.Is(err, ErrIncompatible) {
+			t.Errorf("EstimateUnion() error = %v, want %v", err, ErrIncompatible)
+		}
+		if _, err := a.EstimateIntersection(b); !errors.Is(err, ErrIncompatible) {
+			t.Errorf("EstimateIntersection() error = %v, want %v", err, ErrIncompatible)
+		}
+		if _, err := a.Jaccard(b); !errors.Is(err, ErrIncompatible) {
+			t.Errorf("Jaccard() error = %v, want %v", err, ErrIncompatible)
+		}
+	})
+}
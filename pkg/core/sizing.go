@@ -0,0 +1,62 @@
+package core
+
+import "math"
+
+// maxBits is the largest bit size whose backing storage is addressable as
+// 64-bit words. It is a whole number of blocks, so rounding a size up to
+// whole bytes, words or blocks cannot overflow.
+const maxBits uint64 = min(math.MaxInt/8, math.MaxUint64/64) / blockWords * blockBits
+
+// exceedsMaxBits reports whether a bit size computed in floating point is
+// larger than maxBits. float64(maxBits) may round up to 2^64, so the
+// comparison is strict and a size of exactly float64(maxBits) is rejected.
+func exceedsMaxBits(m float64) bool {
+	return !(m < float64(maxBits))
+}
+
+// optimalBits returns m = -n * ln(p) / ln(2)^2, the bit size that gives
+// false-positive rate p for n elements.
+// see https://en.wikipedia.org/wiki/Bloom_filter#Optimal_number_of_hash_functions
+func optimalBits(n uint64, p float64) float64 {
+	return math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+}
+
+// optimalHashes returns k = (m/n) * ln(2), never less than 1.
+func optimalHashes(m, n uint64) uint32 {
+	k := math.Round(float64(m) / float64(n) * math.Ln2)
+	if k < 1 {
+		return 1
+	}
+	if k > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(k)
+}
+
+// falsePositiveRate returns (1 - e^(-kn/m))^k, the expected false-positive
+// rate of an m-bit filter with k hashes after n insertions.
+func falsePositiveRate(m uint64, k uint32, n uint64) float64 {
+	return math.Pow(1-math.Exp(-float64(k)*float64(n)/float64(m)), float64(k))
+}
+
+// estimateCardinality returns n = -(m/k) * ln(1 - x/m), the Swamidass–Baldi
+// estimate of distinct elements in an m-bit filter with x bits set.
+// see https://en.wikipedia.org/wiki/Bloom_filter#Approximating_the_number_of_items_in_a_Bloom_filter
+func estimateCardinality(m uint64, k uint32, x uint64) float64 {
+	return -float64(m) / float64(k) * math.Log1p(-float64(x)/float64(m))
+}
+
+// cardinalityError returns one standard deviation of the Swamidass–Baldi
+// estimate for x set bits. Treating the k*n bit assignments as balls thrown
+// into m bins, the set-bit count has variance m*q*(1 - (1+t)*q) with
+// q = 1 - x/m and t = -ln q; it is scaled by the estimator's slope (m/k)/(m-x).
+func cardinalityError(m uint64, k uint32, x uint64) float64 {
+	if x >= m {
+		return math.Inf(1)
+	}
+	fm := float64(m)
+	q := 1 - float64(x)/fm
+	t := -math.Log(q)
+	variance := max(fm*q*(1-(1+t)*q), 0)
+	return math.Sqrt(variance) * fm / float64(k) / (fm - float64(x))
+}
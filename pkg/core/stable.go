@@ -0,0 +1,198 @@
+package core
+
+import (
+	ihash "alex/bvs/internal/hash"
+	"alex/bvs/internal/packed"
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+// defaultCellWidth gives cells counting down from 3, so an element survives
+// several decrements of its cells while the filter stays compact.
+const defaultCellWidth = 2
+
+// StableBloomFilter detects duplicates in an unbounded stream with bounded
+// memory. Each insert first decrements P randomly chosen cells, then sets the
+// key's k cells to the maximum cell value, so old elements gradually fade
+// out and the fraction of zero cells converges to a stable point instead of
+// falling to zero. Elements inserted long ago may be reported absent: unlike
+// the other filters it has false negatives as well as false positives.
+// The random decrements are drawn from a generator seeded from the filter's
+// key through a one-way step, so filters built with the same key evolve
+// identically without the decrements giving the key away.
+// see Deng & Rafiei, "Approximately Detecting Duplicates for Streaming Data
+// using Stable Bloom Filters"
+// A StableBloomFilter is not safe for concurrent use.
+type StableBloomFilter[T any] struct {
+	keyHasher[T]
+	cells *packed.Array
+	k     uint32
+	p     uint64
+	rng   *rand.Rand
+}
+
+// StableConfig describes a stable bloom filter. It is sized like a bloom
+// filter, with Bits as the number of cells, which must exceed the hash count.
+type StableConfig struct {
+	Config
+	// CellWidth is the width in bits of each cell, in [1, 32]. Zero
+	// selects 2.
+	CellWidth uint8
+	// Decrements is P, the number of cells decremented per insert, at most
+	// the number of cells. Zero derives it from the cell count, hash count
+	// and width so the stable false-positive rate is at most the configured
+	// one.
+	Decrements uint64
+}
+
+// NewStable creates a stable bloom filter described by cfg with opts applied
+// on top.
+func NewStable[T any](cfg StableConfig, opts ...Option) (*StableBloomFilter[T], error) {
+	p, enc, err := resolveFor[T](&cfg.Config, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	width := cfg.CellWidth
+	if width == 0 {
+		width = defaultCellWidth
+	}
+	if width > 32 {
+		return nil, fmt.Errorf("%w: cell width %d exceeds 32", ErrInvalidWidth, width)
+	}
+	if p.bits > maxBits/uint64(width) {
+		return nil, fmt.Errorf("%w: %d cells of %d bits", ErrFilterTooLarge, p.bits, width)
+	}
+	if p.bits <= uint64(p.k) {
+		return nil, fmt.Errorf("%w: %d cells for %d hash functions", ErrInvalidSize, p.bits, p.k)
+	}
+	if cfg.Decrements > p.bits {
+		return nil, fmt.Errorf("%w: %d decrements exceed %d cells", ErrConflictingConfig, cfg.Decrements, p.bits)
+	}
+
+	decrements := cfg.Decrements
+	if decrements == 0 {
+		decrements = stableDecrements(p.bits, p.k, uint64(1)<<width-1, p.fpRate)
+	}
+
+	seed0, seed1 := rngSeeds(p.key)
+	return &StableBloomFilter[T]{
+		keyHasher: newKeyHasher(p.family, p.key, enc),
+		cells:     packed.NewArray(p.bits, width),
+		k:         p.k,
+		p:         decrements,
+		rng:       rand.New(rand.NewPCG(seed0, seed1)),
+	}, nil
+}
+
+// stableZeros returns the fraction of zero cells at the stable point of a
+// filter with m cells, k hash functions, maximum cell value limit and p
+// decrements per insert.
+func stableZeros(m uint64, k uint32, limit, p uint64) float64 {
+	c := float64(p) * (1/float64(k) - 1/float64(m))
+	return math.Pow(1/(1+1/c), float64(limit))
+}
+
+// stableDecrements returns the smallest P whose stable false-positive rate,
+// (1 - stableZeros)^k, is at most rate, clamped to [1, m].
+func stableDecrements(m uint64, k uint32, limit uint64, rate float64) uint64 {
+	zeros := 1 - math.Pow(rate, 1/float64(k))
+	c := 1/float64(k) - 1/float64(m)
+	p := math.Ceil(1 / ((math.Pow(zeros, -1/float64(limit)) - 1) * c))
+	return uint64(min(max(p, 1), float64(m)))
+}
+
+func (sf *StableBloomFilter[T]) probes(data T) ihash.Probes {
+	h1, h2 := sf.sum(data)
+	return ihash.NewProbes(h1, h2, sf.cells.Len())
+}
+
+// Insert adds an element to the filter. It first ages the filter by
+// decrementing P cells chosen independently at random.
+// It never fails; the error result keeps its signature in line with
+// BloomFilter.Insert.
+func (sf *StableBloomFilter[T]) Insert(data T) error {
+	sf.insertProbes(sf.probes(data))
+	return nil
+}
+
+// TestAndInsert reports whether an element might have been seen recently,
+// as Contains would before the insert, then inserts it.
+func (sf *StableBloomFilter[T]) TestAndInsert(data T) bool {
+	probes := sf.probes(data)
+	seen := sf.containsProbes(probes)
+	sf.insertProbes(probes)
+	return seen
+}
+
+func (sf *StableBloomFilter[T]) insertProbes(probes ihash.Probes) {
+	m := sf.cells.Len()
+	for i := uint64(0); i < sf.p; i++ {
+		index := sf.rng.Uint64N(m)
+		if c := sf.cells.Get(index); c > 0 {
+			sf.cells.Set(index, c-1)
+		}
+	}
+
+	limit := sf.cells.Max()
+	for i := uint32(0); i < sf.k; i++ {
+		sf.cells.Set(probes.Next(), limit)
+	}
+}
+
+// Contains checks if an element might have been inserted recently.
+// Returns true if the element might be present (with possible false positives).
+// Returns false if the element was not inserted or has faded out.
+func (sf *StableBloomFilter[T]) Contains(data T) bool {
+	return sf.containsProbes(sf.probes(data))
+}
+
+func (sf *StableBloomFilter[T]) containsProbes(probes ihash.Probes) bool {
+	for i := uint32(0); i < sf.k; i++ {
+		if sf.cells.Get(probes.Next()) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Size returns the number of cells.
+func (sf *StableBloomFilter[T]) Size() uint64 {
+	return sf.cells.Len()
+}
+
+// CellWidth returns the width of each cell in bits.
+func (sf *StableBloomFilter[T]) CellWidth() uint8 {
+	return sf.cells.Width()
+}
+
+// HashCount returns the number of cells set per element.
+func (sf *StableBloomFilter[T]) HashCount() uint32 {
+	return sf.k
+}
+
+// Decrements returns P, the number of cells decremented per insert.
+func (sf *StableBloomFilter[T]) Decrements() uint64 {
+	return sf.p
+}
+
+// StableFalsePositiveRate returns the false-positive rate the filter
+// converges to once the stream is long enough to reach the stable point,
+// whatever the stream's length or number of distinct elements.
+func (sf *StableBloomFilter[T]) StableFalsePositiveRate() float64 {
+	zeros := stableZeros(sf.cells.Len(), sf.k, sf.cells.Max(), sf.p)
+	return math.Pow(1-zeros, float64(sf.k))
+}
+
+// ZeroRatio returns the current fraction of zero cells. It costs one pass
+// over the cells.
+func (sf *StableBloomFilter[T]) ZeroRatio() float64 {
+	var zeros uint64
+	for i := uint64(0); i < sf.cells.Len(); i++ {
+		if sf.cells.Get(i) == 0 {
+			zeros++
+		}
+	}
+	return float64(zeros) / float64(sf.cells.Len())
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"alex/bvs/pkg/hash"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNewStable(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       StableConfig
+		wantErr   error
+		wantWidth uint8
+		wantP     uint64
+	}{
+		{"default width", StableConfig{Config: Config{Bits: 1 << 12, HashCount: 3}, Decrements: 10}, nil, 2, 10},
+		{"custom width", StableConfig{Config: Config{Bits: 1 << 12, HashCount: 3}, CellWidth: 3, Decrements: 10}, nil, 3, 10},
+		{"derived decrements", StableConfig{Config: Config{Capacity: 1000, FalsePositiveRate: 0.01}}, nil, 2, 0},
+		{"width too large", StableConfig{Config: Config{Bits: 1 << 12}, CellWidth: 33}, ErrInvalidWidth, 0, 0},
+		{"too few cells", StableConfig{Config: Config{Bits: 4, HashCount: 4}}, ErrInvalidSize, 0, 0},
+		{"too many decrements", StableConfig{Config: Config{Bits: 100}, Decrements: 101}, ErrConflictingConfig, 0, 0},
+		{"invalid config", StableConfig{}, ErrInvalidSize, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewStable[string](tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewStable() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if f.CellWidth() != tt.wantWidth {
+				t.Errorf("CellWidth() = %d, want %d", f.CellWidth(), tt.wantWidth)
+			}
+			if tt.wantP != 0 && f.Decrements() != tt.wantP {
+				t.Errorf("Decrements() = %d, want %d", f.Decrements(), tt.wantP)
+			}
+			if f.Decrements() == 0 || f.Decrements() > f.Size() {
+				t.Errorf("Decrements() = %d, want in [1, %d]", f.Decrements(), f.Size())
+			}
+		})
+	}
+}
+
+func TestStableDecrements(t *testing.T) {
+	for _, rate := range []float64{0.001, 0.01, 0.1} {
+		for _, limit := range []uint64{1, 3, 7} {
+			p := stableDecrements(10000, 4, limit, rate)
+			got := math.Pow(1-stableZeros(10000, 4, limit, p), 4)
+			if got > rate {
+				t.Errorf("rate %g, max %d: P = %d gives stable rate %g, want at most %g", rate, limit, p, got, rate)
+			}
+			if p > 1 {
+				looser := math.Pow(1-stableZeros(10000, 4, limit, p-1), 4)
+				if looser <= rate {
+					t.Errorf("rate %g, max %d: P = %d is not the smallest, %d gives %g", rate, limit, p, p-1, looser)
+				}
+			}
+		}
+	}
+}
+
+func TestStableBloomFilter_RecentElements(t *testing.T) {
+	f := Must(NewStable[int](StableConfig{Config: Config{Bits: 1 << 14, HashCount: 3}}))
+	for i := 0; i < 100000; i++ {
+		if err := f.Insert(i); err != nil {
+			t.Fatalf("Insert(%d) error = %v", i, err)
+		}
+		if !f.Contains(i) {
+			t.Fatalf("Contains(%d) = false right after insert, want true", i)
+		}
+		if !f.TestAndInsert(i) {
+			t.Fatalf("TestAndInsert(%d) = false for a repeat, want true", i)
+		}
+	}
+}
+
+func TestStableBloomFilter_StablePoint(t *testing.T) {
+	f := Must(NewStable[int](StableConfig{Config: Config{Bits: 1 << 14, HashCount: 3}, Decrements: 10}))
+	for i := 0; i < 200000; i++ {
+		f.Insert(i)
+	}
+
+	wantZeros := stableZeros(f.Size(), f.HashCount(), 3, f.Decrements())
+	if got := f.ZeroRatio(); math.Abs(got-wantZeros) > 0.02 {
+		t.Errorf("ZeroRatio() = %g, want about %g at the stable point", got, wantZeros)
+	}
+
+	var fp int
+	const probes = 50000
+	for i := 1 << 30; i < 1<<30+probes; i++ {
+		if f.Contains(i) {
+			fp++
+		}
+	}
+	rate, want := float64(fp)/probes, f.StableFalsePositiveRate()
+	if math.Abs(rate-want) > 0.25*want {
+		t.Errorf("false-positive rate = %g, want about %g", rate, want)
+	}
+}
+
+func TestStableBloomFilter_Deterministic(t *testing.T) {
+	a := Must(NewStable[int](StableConfig{Config: Config{Bits: 1 << 10, HashCount: 3}}))
+	b := Must(NewStable[int](StableConfig{Config: Config{Bits: 1 << 10, HashCount: 3}}, WithKey(a.Key())))
+	for i := 0; i < 5000; i++ {
+		a.Insert(i)
+		b.Insert(i)
+	}
+	if a.ZeroRatio() != b.ZeroRatio() {
+		t.Errorf("ZeroRatio() = %g and %g, want filters with the same key to match", a.ZeroRatio(), b.ZeroRatio())
+	}
+}
+
+func TestStableBloomFilter_SeedHidesKey(t *testing.T) {
+	key := hash.KeyFromSeeds(1, 2)
+	s0, s1 := rngSeeds(key)
+	if k0, k1 := key.Seeds(); s0 == k0 || s1 == k1 {
+		t.Errorf("rngSeeds() = %#x, %#x, want seeds unrelated to the key", s0, s1)
+	}
+	if o0, o1 := rngSeeds(hash.KeyFromSeeds(1, 3)); o0 == s0 && o1 == s1 {
+		t.Error("rngSeeds() is the same for different keys")
+	}
+}
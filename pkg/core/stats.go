@@ -0,0 +1,42 @@
+package core
+
+import "math"
+
+// Stats describes how full a filter is and how accurate it currently is.
+type Stats struct {
+	// Bits is the filter size in bits.
+	Bits uint64
+	// BitsSet is the number of bits set to one.
+	BitsSet uint64
+	// FillRatio is BitsSet / Bits.
+	FillRatio float64
+	// HashCount is the number of bit positions per key.
+	HashCount uint32
+	// Inserted counts insertions that set at least one new bit.
+	Inserted uint64
+	// EstimatedCardinality is the Swamidass–Baldi estimate of distinct keys,
+	// +Inf once every bit is set.
+	EstimatedCardinality float64
+	// EstimatedFalsePositiveRate is FillRatio^HashCount, the probability that
+	// a key never inserted is reported as present.
+	EstimatedFalsePositiveRate float64
+}
+
+// Stats reports the filter's current fill and accuracy. It counts set bits,
+// so it costs one pass over the bitset.
+func (bf *BloomFilter[T]) Stats() Stats {
+	return newStats(bf.Size(), bf.bs.Count(), bf.k, bf.elements)
+}
+
+func newStats(m, x uint64, k uint32, inserted uint64) Stats {
+	fill := float64(x) / float64(m)
+	return Stats{
+		Bits:                       m,
+		BitsSet:                    x,
+		FillRatio:                  fill,
+		HashCount:                  k,
+		Inserted:                   inserted,
+		EstimatedCardinality:       estimateCardinality(m, k, x),
+		EstimatedFalsePositiveRate: math.Pow(fill, float64(k)),
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBloomFilter_Stats(t *testing.T) {
+	t.Run("empty filter", func(t *testing.T) {
+		s := NewBloomFilter[int](1024).Stats()
+		want := Stats{Bits: 1024, HashCount: 7}
+		if s != want {
+			t.Errorf("Stats() = %+v, want %+v", s, want)
+		}
+	})
+
+	t.Run("single insert", func(t *testing.T) {
+		f := NewBloomFilter[int](1 << 16)
+		f.Insert(1)
+		s := f.Stats()
+		if s.BitsSet == 0 || s.BitsSet > uint64(s.HashCount) {
+			t.Errorf("BitsSet = %d, want in [1, %d]", s.BitsSet, s.HashCount)
+		}
+		if s.Inserted != 1 {
+			t.Errorf("Inserted = %d, want 1", s.Inserted)
+		}
+		if math.Abs(s.EstimatedCardinality-1) > 0.1 {
+			t.Errorf("EstimatedCardinality = %g, want about 1", s.EstimatedCardinality)
+		}
+	})
+
+	t.Run("estimates track inserts", func(t *testing.T) {
+		const n = 5000
+		f := Must(NewWithEstimates[int](n, 0.01))
+		for i := 0; i < n; i++ {
+			f.Insert(i)
+		}
+		s := f.Stats()
+		if s.FillRatio != float64(s.BitsSet)/float64(s.Bits) {
+			t.Errorf("FillRatio = %g, want %g", s.FillRatio, float64(s.BitsSet)/float64(s.Bits))
+		}
+		if math.Abs(s.FillRatio-0.5) > 0.05 {
+			t.Errorf("FillRatio = %g, want about 0.5 at capacity", s.FillRatio)
+		}
+		if math.Abs(s.EstimatedCardinality-n)/n > 0.05 {
+			t.Errorf("EstimatedCardinality = %g, want within 5%% of %d", s.EstimatedCardinality, n)
+		}
+		if math.Abs(s.EstimatedFalsePositiveRate-0.01) > 0.005 {
+			t.Errorf("EstimatedFalsePositiveRate = %g, want about 0.01", s.EstimatedFalsePositiveRate)
+		}
+	})
+
+	t.Run("saturated filter", func(t *testing.T) {
+		f := NewBloomFilter[int](16)
+		for i := 0; i < 1000; i++ {
+			f.Insert(i)
+		}
+		s := f.Stats()
+		if s.BitsSet != 16 || s.FillRatio != 1 {
+			t.Errorf("BitsSet = %d, FillRatio = %g, want 16, 1", s.BitsSet, s.FillRatio)
+		}
+		if !math.IsInf(s.EstimatedCardinality, 1) {
+			t.Errorf("EstimatedCardinality = %g, want +Inf", s.EstimatedCardinality)
+		}
+		if s.EstimatedFalsePositiveRate != 1 {
+			t.Errorf("EstimatedFalsePositiveRate = %g, want 1", s.EstimatedFalsePositiveRate)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"alex/bvs/pkg/hash"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+const (
+	// strataCount is the number of strata, enough for differences up to
+	// about 2^32 keys.
+	strataCount = 32
+	// strataCells and strataHashCount size the IBLT of each stratum; a
+	// stratum that holds up to about 50 keys of the difference decodes.
+	strataCells     = 80
+	strataHashCount = 4
+)
+
+// StrataEstimator estimates the size of the difference between two sets so
+// that an IBLT can be sized for it. Keys are split into strata, stratum i
+// receiving a fraction 2^-(i+1) of them, and each stratum keeps a small IBLT.
+// Subtracting two estimators and decoding from the sparsest stratum down
+// counts the difference exactly until a stratum fails to decode, then scales
+// the count by the fraction of keys sampled so far.
+// see Eppstein et al., "What's the Difference? Efficient Set Reconciliation without Prior Context"
+// A StrataEstimator is not safe for concurrent use.
+type StrataEstimator struct {
+	strata [strataCount]*IBLT
+}
+
+// NewStrataEstimator creates an empty strata estimator. Only the hashing
+// options apply: WithEncoder, WithHashFamily, WithKey, WithSecretKey and
+// WithFixedKey. Two estimators can only be compared if they hash keys
+// identically; build the second with WithKey and the first one's Key.
+func NewStrataEstimator(opts ...Option) (*StrataEstimator, error) {
+	cfg := optionsConfig(opts)
+	kh, err := keyHasherFor[uint64](cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newStrataEstimator(kh), nil
+}
+
+func newStrataEstimator(kh keyHasher[uint64]) *StrataEstimator {
+	var se StrataEstimator
+	for i := range se.strata {
+		se.strata[i] = newIBLT(newKeyHasher(kh.family, kh.key, kh.enc), strataHashCount, strataCells/strataHashCount, strataCells)
+	}
+	return &se
+}
+
+// update adds key to its stratum count times.
+func (se *StrataEstimator) update(key uint64, count int64) {
+	// Every stratum hashes identically, so the first one serves for all.
+	h := se.strata[0].hashes(key)
+	i := min(bits.TrailingZeros64(h.check), strataCount-1)
+	se.strata[i].apply(se.strata[i].cells, key, h, count)
+}
+
+// Insert adds key to the estimator.
+func (se *StrataEstimator) Insert(key uint64) {
+	se.update(key, 1)
+}
+
+// Delete removes key from the estimator.
+func (se *StrataEstimator) Delete(key uint64) {
+	se.update(key, -1)
+}
+
+// Compatible reports whether se and other hash keys identically.
+func (se *StrataEstimator) Compatible(other *StrataEstimator) error {
+	return se.strata[0].Compatible(other.strata[0])
+}
+
+// Estimate returns the estimated number of keys in exactly one of the sets
+// held by se and other. Differences of up to about 50 keys are counted
+// exactly.
+func (se *StrataEstimator) Estimate(other *StrataEstimator) (uint64, error) {
+	if err := se.Compatible(other); err != nil {
+		return 0, err
+	}
+	var count uint64
+	for i := strataCount - 1; i >= 0; i-- {
+		diff := se.strata[i].Clone()
+		if err := diff.Subtract(other.strata[i]); err != nil {
+			return 0, err
+		}
+		inserted, deleted, err := diff.Decode()
+		if err != nil {
+			return count << (i + 1), nil
+		}
+		count += uint64(len(inserted) + len(deleted))
+	}
+	return count, nil
+}
+
+// Encoder returns the encoder the estimator uses for keys.
+func (se *StrataEstimator) Encoder() Encoder[uint64] {
+	return se.strata[0].Encoder()
+}
+
+// HashFamily returns the name of the hash family the estimator was built with.
+func (se *StrataEstimator) HashFamily() string {
+	return se.strata[0].HashFamily()
+}
+
+// Key returns the key the estimator's hasher is seeded from. Pass it to
+// WithKey to build a compatible estimator or IBLT.
+func (se *StrataEstimator) Key() hash.Key {
+	return se.strata[0].Key()
+}
+
+// Size returns the total bit size of the strata.
+func (se *StrataEstimator) Size() uint64 {
+	return strataCount * se.strata[0].Size()
+}
+
+func (se *StrataEstimator) header() header {
+	h := se.strata[0].header()
+	h.layout = layoutStrataEstimator
+	h.bits = se.Size()
+	return h
+}
+
+// WriteTo writes the estimator to w in the serialized format, with the
+// strata estimator layout.
+func (se *StrataEstimator) WriteTo(w io.Writer) (int64, error) {
+	payload := make([]byte, 0, se.Size()/8)
+	for _, t := range se.strata {
+		payload = appendCells(payload, t.cells)
+	}
+	return writeFilter(w, se.header(), payload)
+}
+
+// ReadFrom replaces the estimator with one read from r in the serialized
+// format. The encoder is chosen as for BloomFilter.ReadFrom.
+func (se *StrataEstimator) ReadFrom(r io.Reader) (int64, error) {
+	var enc Encoder[uint64]
+	if se.strata[0] != nil {
+		enc = se.strata[0].enc
+	}
+	d, n, err := readFilter(r, layoutStrataEstimator, enc)
+	if err != nil {
+		return n, err
+	}
+	if d.k != strataHashCount || d.bits != strataCount*strataCells*ibltCellBits {
+		return n, fmt.Errorf("%w: %d bits with hash count %d", ErrInvalidFormat, d.bits, d.k)
+	}
+
+	*se = *newStrataEstimator(d.keyHasher)
+	for i, t := range se.strata {
+		readCells(t.cells, d.payload[i*strataCells*ibltCellBits/8:])
+	}
+	return n, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (se *StrataEstimator) MarshalBinary() ([]byte, error) {
+	return marshal(64+len(se.HashFamily())+int(se.Size()/8), se.WriteTo)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. See ReadFrom.
+func (se *StrataEstimator) UnmarshalBinary(data []byte) error {
+	return unmarshal(data, se.ReadFrom)
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestStrataEstimator_Estimate(t *testing.T) {
+	tests := []struct {
+		diff     int
+		min, max uint64
+	}{
+		{0, 0, 0},
+		{1, 1, 1},
+		{30, 30, 30},
+		{1000, 500, 2000},
+		{20000, 10000, 40000},
+	}
+
+	for _, tt := range tests {
+		r := rand.New(rand.NewPCG(uint64(tt.diff), 9))
+		keys := randomKeys(r, 10000+tt.diff)
+		a := Must(NewStrataEstimator(WithFixedKey()))
+		b := Must(NewStrataEstimator(WithFixedKey()))
+		for _, key := range keys[:10000] {
+			a.Insert(key)
+			b.Insert(key)
+		}
+		for i, key := range keys[10000:] {
+			if i%2 == 0 {
+				a.Insert(key)
+			} else {
+				b.Insert(key)
+			}
+		}
+
+		got, err := a.Estimate(b)
+		if err != nil {
+			t.Fatalf("diff=%d: Estimate() error = %v", tt.diff, err)
+		}
+		if got < tt.min || got > tt.max {
+			t.Errorf("diff=%d: Estimate() = %d, want in [%d, %d]", tt.diff, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestStrataEstimator_Delete(t *testing.T) {
+	a := Must(NewStrataEstimator(WithFixedKey()))
+	b := Must(NewStrataEstimator(WithFixedKey()))
+	for key := range uint64(500) {
+		a.Insert(key)
+		b.Insert(key)
+	}
+	for key := range uint64(10) {
+		a.Delete(key)
+	}
+	if got, err := a.Estimate(b); err != nil || got != 10 {
+		t.Errorf("Estimate() = %d, %v, want 10", got, err)
+	}
+}
+
+func TestStrataEstimator_SizesIBLT(t *testing.T) {
+	r := rand.New(rand.NewPCG(10, 11))
+	keys := randomKeys(r, 5300)
+	common, onlyA, onlyB := keys[:5000], keys[5000:5200], keys[5200:]
+
+	ea, eb := Must(NewStrataEstimator(WithFixedKey())), Must(NewStrataEstimator(WithFixedKey()))
+	for _, key := range slices.Concat(common, onlyA) {
+		ea.Insert(key)
+	}
+	for _, key := range slices.Concat(common, onlyB) {
+		eb.Insert(key)
+	}
+	d, err := ea.Estimate(eb)
+	if err != nil {
+		t.Fatalf("Estimate() error = %v", err)
+	}
+
+	// Leave headroom for the estimator's error.
+	a, b, err := reconcile(t, IBLTConfig{Capacity: 2 * d}, common, onlyA, onlyB)
+	if err != nil {
+		t.Fatalf("Decode() with estimate %d error = %v", d, err)
+	}
+	if len(a) != len(onlyA) || len(b) != len(onlyB) {
+		t.Errorf("Decode() = %d, %d keys, want %d, %d", len(a), len(b), len(onlyA), len(onlyB))
+	}
+}
+
+func TestStrataEstimator_MarshalRoundTrip(t *testing.T) {
+	a := Must(NewStrataEstimator(WithFixedKey()))
+	b := Must(NewStrataEstimator(WithFixedKey()))
+	for key := range uint64(1000) {
+		a.Insert(key)
+		b.Insert(key + 20)
+	}
+
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	var received StrataEstimator
+	if err := received.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if got, err := a.Estimate(&received); err != nil || got != 40 {
+		t.Errorf("Estimate() = %d, %v, want 40", got, err)
+	}
+
+	var ie *IncompatibleError
+	if _, err := a.Estimate(Must(NewStrataEstimator())); !errors.As(err, &ie) || ie.Field != "key" {
+		t.Errorf("Estimate() error = %v, want IncompatibleError on key", err)
+	}
+	table, _ := Must(NewIBLT(IBLTConfig{Capacity: 10})).MarshalBinary()
+	if err := received.UnmarshalBinary(table); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("UnmarshalBinary(IBLT) error = %v, want %v", err, ErrInvalidFormat)
+	}
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultGenerations keeps the current generation and the one before it.
+const defaultGenerations = 2
+
+// WindowedFilter remembers elements for a limited time. It keeps a ring of
+// bloom filter generations: inserts go to the newest, lookups check them
+// all, and every RotationInterval the oldest generation is dropped and a new
+// empty one started. An element is therefore reported present for at least
+// (Generations-1) intervals and at most Generations intervals after its
+// last insert. Each generation is sized by the Config, so Capacity is the
+// number of distinct elements expected per interval.
+// Rotation happens lazily, on the first call after an interval has passed.
+// A WindowedFilter is not safe for concurrent use.
+type WindowedFilter[T any] struct {
+	keyHasher[T]
+	gens     []*BloomFilter[T]
+	newest   int
+	p        params
+	interval time.Duration
+	now      func() time.Time
+	rotated  time.Time
+}
+
+// WindowedConfig describes a windowed filter.
+type WindowedConfig struct {
+	Config
+	// RotationInterval is how often a new generation is started. It must be
+	// positive.
+	RotationInterval time.Duration
+	// Generations is the number of generations kept, at least 1. Zero
+	// selects 2.
+	Generations int
+}
+
+// NewWindowed creates a windowed filter described by cfg with opts applied
+// on top. The clock defaults to time.Now; see WithClock.
+func NewWindowed[T any](cfg WindowedConfig, opts ...Option) (*WindowedFilter[T], error) {
+	p, enc, err := resolveFor[T](&cfg.Config, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	gens := cfg.Generations
+	if gens == 0 {
+		gens = defaultGenerations
+	}
+	if gens < 1 {
+		return nil, fmt.Errorf("%w: %d generations, want at least 1", ErrInvalidWindow, gens)
+	}
+	if cfg.RotationInterval <= 0 {
+		return nil, fmt.Errorf("%w: rotation interval %v, want positive", ErrInvalidWindow, cfg.RotationInterval)
+	}
+	now := cfg.now
+	if now == nil {
+		now = time.Now
+	}
+
+	wf := &WindowedFilter[T]{
+		keyHasher: newKeyHasher(p.family, p.key, enc),
+		gens:      make([]*BloomFilter[T], gens),
+		p:         p,
+		interval:  cfg.RotationInterval,
+		now:       now,
+		rotated:   now(),
+	}
+	for i := range wf.gens {
+		wf.gens[i] = newBloomFilter(p, enc)
+	}
+	return wf, nil
+}
+
+// advance rotates once for every interval elapsed since the last rotation.
+func (wf *WindowedFilter[T]) advance() {
+	elapsed := wf.now().Sub(wf.rotated)
+	if elapsed < wf.interval {
+		return
+	}
+
+	periods := elapsed / wf.interval
+	for range min(periods, time.Duration(len(wf.gens))) {
+		wf.rotate()
+	}
+	wf.rotated = wf.rotated.Add(periods * wf.interval)
+}
+
+func (wf *WindowedFilter[T]) rotate() {
+	wf.newest = (wf.newest + 1) % len(wf.gens)
+	wf.gens[wf.newest] = newBloomFilter(wf.p, wf.enc)
+}
+
+// Rotate drops the oldest generation and starts a new one immediately,
+// restarting the rotation interval.
+func (wf *WindowedFilter[T]) Rotate() {
+	wf.rotate()
+	wf.rotated = wf.now()
+}
+
+// Insert adds an element to the newest generation, renewing it for another
+// window if it was already present.
+func (wf *WindowedFilter[T]) Insert(data T) error {
+	wf.advance()
+	return wf.gens[wf.newest].insertSum(wf.sum(data))
+}
+
+// Contains checks if an element might have been inserted within the window.
+// Returns true if the element might be present (with possible false positives).
+// Returns false if the element was not inserted or has expired.
+func (wf *WindowedFilter[T]) Contains(data T) bool {
+	wf.advance()
+	h1, h2 := wf.sum(data)
+	for _, gen := range wf.gens {
+		if gen.containsSum(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+// Generations returns the number of generations kept.
+func (wf *WindowedFilter[T]) Generations() int {
+	return len(wf.gens)
+}
+
+// RotationInterval returns how often a new generation is started.
+func (wf *WindowedFilter[T]) RotationInterval() time.Duration {
+	return wf.interval
+}
+
+// Window returns the longest time an element stays present after its last
+// insert: Generations times RotationInterval.
+func (wf *WindowedFilter[T]) Window() time.Duration {
+	return time.Duration(len(wf.gens)) * wf.interval
+}
+
+// Size returns the total bit size of all generations.
+func (wf *WindowedFilter[T]) Size() uint64 {
+	return uint64(len(wf.gens)) * wf.p.bits
+}
+
+// FalsePositiveRate returns the false-positive rate expected once every
+// generation holds Capacity elements: the chance that any of them reports
+// a false positive.
+func (wf *WindowedFilter[T]) FalsePositiveRate() float64 {
+	pass := 1.0
+	for range wf.gens {
+		pass *= 1 - wf.p.fpRate
+	}
+	return 1 - pass
+}
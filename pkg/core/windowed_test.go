@@ -0,0 +1,134 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for WithClock.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func TestNewWindowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      WindowedConfig
+		wantErr  error
+		wantGens int
+	}{
+		{"default generations", WindowedConfig{Config: Config{Bits: 1024}, RotationInterval: time.Minute}, nil, 2},
+		{"custom generations", WindowedConfig{Config: Config{Bits: 1024}, RotationInterval: time.Minute, Generations: 5}, nil, 5},
+		{"no interval", WindowedConfig{Config: Config{Bits: 1024}}, ErrInvalidWindow, 0},
+		{"negative interval", WindowedConfig{Config: Config{Bits: 1024}, RotationInterval: -time.Second}, ErrInvalidWindow, 0},
+		{"negative generations", WindowedConfig{Config: Config{Bits: 1024}, RotationInterval: time.Minute, Generations: -1}, ErrInvalidWindow, 0},
+		{"invalid config", WindowedConfig{Config: Config{}, RotationInterval: time.Minute}, ErrInvalidSize, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewWindowed[string](tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewWindowed() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if f.Generations() != tt.wantGens {
+				t.Errorf("Generations() = %d, want %d", f.Generations(), tt.wantGens)
+			}
+			if f.Window() != time.Duration(tt.wantGens)*time.Minute {
+				t.Errorf("Window() = %v, want %v", f.Window(), time.Duration(tt.wantGens)*time.Minute)
+			}
+		})
+	}
+}
+
+func TestWindowedFilter_Expiry(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	f := Must(NewWindowed[string](WindowedConfig{
+		Config:           Config{Capacity: 1000, FalsePositiveRate: 0.001},
+		RotationInterval: 5 * time.Minute,
+		Generations:      2,
+	}, WithClock(clock.now)))
+
+	f.Insert("early")
+	clock.advance(4 * time.Minute)
+	f.Insert("late")
+
+	clock.advance(2 * time.Minute) // 6m: one rotation
+	if !f.Contains("early") || !f.Contains("late") {
+		t.Fatal("Contains() = false after one rotation, want both present")
+	}
+
+	clock.advance(4 * time.Minute) // 10m: two rotations
+	if f.Contains("early") || f.Contains("late") {
+		t.Error("Contains() = true after two rotations, want both expired")
+	}
+}
+
+func TestWindowedFilter_Renew(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	f := Must(NewWindowed[string](WindowedConfig{
+		Config:           Config{Capacity: 1000, FalsePositiveRate: 0.001},
+		RotationInterval: time.Minute,
+		Generations:      3,
+	}, WithClock(clock.now)))
+
+	f.Insert("key")
+	for i := 0; i < 10; i++ {
+		clock.advance(time.Minute)
+		f.Insert("key")
+	}
+	clock.advance(2 * time.Minute)
+	if !f.Contains("key") {
+		t.Error("Contains() = false for a renewed key within the window, want true")
+	}
+	clock.advance(time.Minute)
+	if f.Contains("key") {
+		t.Error("Contains() = true after the window passed, want false")
+	}
+}
+
+func TestWindowedFilter_LongIdle(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	f := Must(NewWindowed[int](WindowedConfig{Config: Config{Bits: 1024}, RotationInterval: time.Second, Generations: 4},
+		WithClock(clock.now)))
+
+	f.Insert(1)
+	clock.advance(time.Hour + time.Second/2)
+	if f.Contains(1) {
+		t.Error("Contains() = true after a long idle period, want false")
+	}
+
+	// The interval stays aligned to the original schedule.
+	f.Insert(2)
+	clock.advance(time.Second / 2)
+	f.Insert(3)
+	clock.advance(3 * time.Second)
+	if f.Contains(2) || !f.Contains(3) {
+		t.Errorf("Contains(2), Contains(3) = %v, %v, want false, true", f.Contains(2), f.Contains(3))
+	}
+}
+
+func TestWindowedFilter_Rotate(t *testing.T) {
+	f := Must(NewWindowed[int](WindowedConfig{Config: Config{Bits: 1024}, RotationInterval: time.Hour}))
+	f.Insert(1)
+	f.Rotate()
+	if !f.Contains(1) {
+		t.Fatal("Contains(1) = false after one Rotate, want true")
+	}
+	f.Rotate()
+	if f.Contains(1) {
+		t.Error("Contains(1) = true after two Rotates, want false")
+	}
+}
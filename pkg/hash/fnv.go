@@ -0,0 +1,76 @@
+package hash
+
+import (
+	"encoding/binary"
+	stdhash "hash"
+	"sync"
+)
+
+const (
+	fnvOffset64 uint64 = 14695981039346656037
+	fnvPrime64  uint64 = 1099511628211
+)
+
+type fnvFamily struct{}
+
+func (fnvFamily) Name() string { return FNV1a }
+
+func (fnvFamily) New(seed0, seed1 uint64) Hasher {
+	return fnvHasher{seed0: seed0, seed1: seed1}
+}
+
+// fnvHasher computes 64-bit FNV-1a with seed0 folded into the offset
+// basis; a zero seed0 gives the reference output. It is the fastest
+// family for short keys but has no resistance to crafted inputs.
+type fnvHasher struct {
+	seed0, seed1 uint64
+}
+
+func (h fnvHasher) Sum128(data []byte) (uint64, uint64) {
+	h1 := fnvOffset64 ^ h.seed0
+	for _, c := range data {
+		h1 ^= uint64(c)
+		h1 *= fnvPrime64
+	}
+	return h1, mix64(h1 ^ h.seed1)
+}
+
+// FromHash64 adapts a standard library hash.Hash64 constructor into a family
+// registered as name. The seed0 bytes are written ahead of the data and the
+// second digest half is derived from Sum64 and seed1. Hash states are pooled,
+// so the resulting Hashers are safe for concurrent use.
+func FromHash64(name string, newHash func() stdhash.Hash64) Family {
+	return hash64Family{
+		name: name,
+		pool: &sync.Pool{New: func() any { return newHash() }},
+	}
+}
+
+type hash64Family struct {
+	name string
+	pool *sync.Pool
+}
+
+func (f hash64Family) Name() string { return f.name }
+
+func (f hash64Family) New(seed0, seed1 uint64) Hasher {
+	return hash64Hasher{pool: f.pool, seed0: seed0, seed1: seed1}
+}
+
+type hash64Hasher struct {
+	pool         *sync.Pool
+	seed0, seed1 uint64
+}
+
+func (h hash64Hasher) Sum128(data []byte) (uint64, uint64) {
+	st := h.pool.Get().(stdhash.Hash64)
+	defer h.pool.Put(st)
+
+	st.Reset()
+	var seed [8]byte
+	binary.LittleEndian.PutUint64(seed[:], h.seed0)
+	st.Write(seed[:])
+	st.Write(data)
+	h1 := st.Sum64()
+	return h1, mix64(h1 ^ h.seed1)
+}
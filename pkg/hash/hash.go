@@ -0,0 +1,93 @@
+// Package hash provides the hash families filters are built on and a registry
+// that maps family names to implementations, so a filter can record which
+// family it was built with and be rebuilt from that name.
+package hash
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Hasher computes 128-bit digests, returned as two 64-bit halves.
+// Hashers must be safe for concurrent use.
+type Hasher interface {
+	Sum128(data []byte) (uint64, uint64)
+}
+
+// Family is a hash algorithm that produces seeded Hashers.
+type Family interface {
+	// Name identifies the family in the registry and in serialized filters.
+	Name() string
+	// New returns a Hasher seeded with seed0 and seed1.
+	New(seed0, seed1 uint64) Hasher
+}
+
+// Names of the built-in families.
+const (
+	SipHash  = "siphash-2-4"
+	XXHash64 = "xxhash64"
+	Murmur3  = "murmur3-x64-128"
+	FNV1a    = "fnv1a-64"
+)
+
+// ErrFamilyExists is returned when registering a family under a taken name.
+var ErrFamilyExists = errors.New("hash family already registered")
+
+var registry = struct {
+	sync.RWMutex
+	families map[string]Family
+}{
+	families: map[string]Family{
+		SipHash:  sipFamily{},
+		XXHash64: xxhashFamily{},
+		Murmur3:  murmurFamily{},
+		FNV1a:    fnvFamily{},
+	},
+}
+
+// Register adds f to the registry under f.Name().
+func Register(f Family) error {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.families[f.Name()]; ok {
+		return fmt.Errorf("%w: %q", ErrFamilyExists, f.Name())
+	}
+	registry.families[f.Name()] = f
+	return nil
+}
+
+// Lookup returns the family registered under name.
+func Lookup(name string) (Family, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	f, ok := registry.families[name]
+	return f, ok
+}
+
+// Names returns the names of all registered families in sorted order.
+func Names() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.families))
+	for name := range registry.families {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// mix64 is the murmur3 finalizer. 64-bit families use it to derive the second
+// digest half from the first, since double hashing needs two values.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,142 @@
+package hash
+
+import (
+	"errors"
+	stdhash "hash"
+	"hash/crc64"
+	"hash/fnv"
+	"slices"
+	"testing"
+)
+
+func TestXXH64(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		seed  uint64
+		want  uint64
+	}{
+		{"empty", "", 0, 0xef46db3751d8e999},
+		{"one byte", "a", 0, 0xd24ec4f1a98c6e5b},
+		{"three bytes", "abc", 0, 0x44bc2cf5ad770999},
+		{"long input", "Nobody inspects the spammish repetition", 0, 0xfbcea83c8a378bf1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := xxh64([]byte(tt.input), tt.seed); got != tt.want {
+				t.Errorf("xxh64(%q) = %#x, want %#x", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMurmur3(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want1 uint64
+		want2 uint64
+	}{
+		{"empty", "", 0, 0},
+		{"short", "hello", 0xcbd8a7b341bd9b02, 0x5b1e906a48ae1d19},
+		{"tail over 8 bytes", "hello, world", 0x342fac623a5ebc8e, 0x4cdcbc079642414d},
+		{"multiple blocks", "19 Jan 2038 at 3:14:07 AM", 0xb89e5988b737affc, 0x664fc2950231b2cb},
+		{"sentence", "The quick brown fox jumps over the lazy dog.", 0xcd99481f9ee902c9, 0x695da1a38987b6e7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h1, h2 := murmur3([]byte(tt.input), 0, 0)
+			if h1 != tt.want1 || h2 != tt.want2 {
+				t.Errorf("murmur3(%q) = (%#x, %#x), want (%#x, %#x)", tt.input, h1, h2, tt.want1, tt.want2)
+			}
+		})
+	}
+}
+
+func TestFNV1a(t *testing.T) {
+	for _, input := range []string{"", "a", "hello world"} {
+		t.Run(input, func(t *testing.T) {
+			ref := fnv.New64a()
+			ref.Write([]byte(input))
+			if got, _ := (fnvHasher{}).Sum128([]byte(input)); got != ref.Sum64() {
+				t.Errorf("Sum128(%q) = %#x, want %#x", input, got, ref.Sum64())
+			}
+		})
+	}
+}
+
+func TestFamilies(t *testing.T) {
+	crc := FromHash64("crc64-iso", func() stdhash.Hash64 { return crc64.New(crc64.MakeTable(crc64.ISO)) })
+	families := []Family{sipFamily{}, xxhashFamily{}, murmurFamily{}, fnvFamily{}, crc}
+
+	for _, f := range families {
+		t.Run(f.Name(), func(t *testing.T) {
+			h := f.New(1, 2)
+			a1, a2 := h.Sum128([]byte("key"))
+			b1, b2 := h.Sum128([]byte("key"))
+			if a1 != b1 || a2 != b2 {
+				t.Error("Sum128() is not deterministic")
+			}
+			if c1, c2 := h.Sum128([]byte("other")); c1 == a1 && c2 == a2 {
+				t.Error("Sum128() collides for different inputs")
+			}
+			if d1, d2 := f.New(3, 4).Sum128([]byte("key")); d1 == a1 && d2 == a2 {
+				t.Error("Sum128() ignores seeds")
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{SipHash, XXHash64, Murmur3, FNV1a} {
+		f, ok := Lookup(name)
+		if !ok {
+			t.Fatalf("Lookup(%q) not found", name)
+		}
+		if f.Name() != name {
+			t.Errorf("Lookup(%q).Name() = %q", name, f.Name())
+		}
+	}
+
+	if _, ok := Lookup("unknown"); ok {
+		t.Error("Lookup(unknown) found, want not found")
+	}
+
+	f := FromHash64("fnv64-test", fnv.New64)
+	if err := Register(f); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := Register(f); !errors.Is(err, ErrFamilyExists) {
+		t.Errorf("Register() twice error = %v, want %v", err, ErrFamilyExists)
+	}
+	if !slices.Contains(Names(), "fnv64-test") {
+		t.Errorf("Names() = %v, want to contain fnv64-test", Names())
+	}
+}
+
+func TestKey(t *testing.T) {
+	k := KeyFromSeeds(1, 2)
+	if s0, s1 := k.Seeds(); s0 != 1 || s1 != 2 {
+		t.Errorf("Seeds() = (%d, %d), want (1, 2)", s0, s1)
+	}
+
+	a, err := NewKey()
+	if err != nil {
+		t.Fatalf("NewKey() error = %v", err)
+	}
+	b, err := NewKey()
+	if err != nil {
+		t.Fatalf("NewKey() error = %v", err)
+	}
+	if a == b {
+		t.Error("NewKey() returned the same key twice")
+	}
+
+	h0, h1 := NewKeyed(sipFamily{}, k).Sum128([]byte("key"))
+	w0, w1 := sipFamily{}.New(1, 2).Sum128([]byte("key"))
+	if h0 != w0 || h1 != w1 {
+		t.Error("NewKeyed() does not seed from the key")
+	}
+}
@@ -0,0 +1,39 @@
+package hash
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+)
+
+// Key is a 128-bit secret a Hasher is seeded from. Filters keyed with a
+// secret the attacker does not know cannot be probed or saturated with
+// crafted inputs; this only holds for a keyed PRF such as SipHash.
+type Key [16]byte
+
+// NewKey returns a random key read from crypto/rand.
+func NewKey() (Key, error) {
+	var k Key
+	if _, err := rand.Read(k[:]); err != nil {
+		return Key{}, fmt.Errorf("generating key: %w", err)
+	}
+	return k, nil
+}
+
+// KeyFromSeeds packs two seeds into a key, little-endian.
+func KeyFromSeeds(seed0, seed1 uint64) Key {
+	var k Key
+	binary.LittleEndian.PutUint64(k[:8], seed0)
+	binary.LittleEndian.PutUint64(k[8:], seed1)
+	return k
+}
+
+// Seeds splits the key into the two seeds passed to Family.New.
+func (k Key) Seeds() (uint64, uint64) {
+	return binary.LittleEndian.Uint64(k[:8]), binary.LittleEndian.Uint64(k[8:])
+}
+
+// NewKeyed returns a Hasher of family f seeded from k.
+func NewKeyed(f Family, k Key) Hasher {
+	return f.New(k.Seeds())
+}
@@ -0,0 +1,88 @@
+package hash
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	murmurC1 uint64 = 0x87c37b91114253d5
+	murmurC2 uint64 = 0x4cf5ad432745937f
+)
+
+type murmurFamily struct{}
+
+func (murmurFamily) Name() string { return Murmur3 }
+
+func (murmurFamily) New(seed0, seed1 uint64) Hasher {
+	return murmurHasher{seed0: seed0, seed1: seed1}
+}
+
+// murmurHasher computes MurmurHash3 x64_128 with its two lanes seeded
+// by seed0 and seed1; equal seeds give the reference output.
+type murmurHasher struct {
+	seed0, seed1 uint64
+}
+
+func (h murmurHasher) Sum128(data []byte) (uint64, uint64) {
+	return murmur3(data, h.seed0, h.seed1)
+}
+
+// murmur3 implements MurmurHash3_x64_128.
+// see https://github.com/aappleby/smhasher/blob/master/src/MurmurHash3.cpp
+func murmur3(b []byte, seed0, seed1 uint64) (uint64, uint64) {
+	n := len(b)
+	h1, h2 := seed0, seed1
+
+	for len(b) >= 16 {
+		k1 := binary.LittleEndian.Uint64(b)
+		k2 := binary.LittleEndian.Uint64(b[8:])
+		b = b[16:]
+
+		h1 ^= murmurMixK1(k1)
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		h2 ^= murmurMixK2(k2)
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	var k1, k2 uint64
+	for i := len(b) - 1; i >= 8; i-- {
+		k2 ^= uint64(b[i]) << (8 * (i - 8))
+	}
+	if len(b) > 8 {
+		h2 ^= murmurMixK2(k2)
+	}
+	for i := min(len(b), 8) - 1; i >= 0; i-- {
+		k1 ^= uint64(b[i]) << (8 * i)
+	}
+	if len(b) > 0 {
+		h1 ^= murmurMixK1(k1)
+	}
+
+	h1 ^= uint64(n)
+	h2 ^= uint64(n)
+	h1 += h2
+	h2 += h1
+	h1 = mix64(h1)
+	h2 = mix64(h2)
+	h1 += h2
+	h2 += h1
+	return h1, h2
+}
+
+func murmurMixK1(k uint64) uint64 {
+	k *= murmurC1
+	k = bits.RotateLeft64(k, 31)
+	return k * murmurC2
+}
+
+func murmurMixK2(k uint64) uint64 {
+	k *= murmurC2
+	k = bits.RotateLeft64(k, 33)
+	return k * murmurC1
+}